@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mgoltzsche/knowledgebot/internal/localstore"
+	"github.com/mgoltzsche/knowledgebot/internal/qdrantutils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	collectionCmd = &cobra.Command{
+		Use:   "collection",
+		Short: "Manage the vector store collections",
+		Long: `Manage the vector store collections.
+Commands that operate on a single collection use the configured collection (--qdrant-collection) unless a name is specified.`,
+	}
+	collectionListCmd = &cobra.Command{
+		Use:     "list",
+		Short:   "List the collections",
+		Args:    cobra.ExactArgs(0),
+		RunE:    listCollections,
+		PreRunE: preRunCollection,
+	}
+	collectionInfoCmd = &cobra.Command{
+		Use:     "info [NAME]",
+		Short:   "Show the configuration and size of a collection",
+		Args:    cobra.MaximumNArgs(1),
+		RunE:    showCollectionInfo,
+		PreRunE: preRunCollection,
+	}
+	collectionCreateCmd = &cobra.Command{
+		Use:     "create [NAME]",
+		Short:   "Create a collection unless it exists",
+		Args:    cobra.MaximumNArgs(1),
+		RunE:    createCollection,
+		PreRunE: preRunCollection,
+	}
+	collectionDeleteCmd = &cobra.Command{
+		Use:     "delete [NAME]",
+		Short:   "Delete a collection along with all of its data",
+		Args:    cobra.MaximumNArgs(1),
+		RunE:    deleteCollection,
+		PreRunE: preRunCollection,
+	}
+	collectionSnapshotCmd = &cobra.Command{
+		Use:   "snapshot [NAME]",
+		Short: "Create a snapshot of a collection",
+		Long: `Create a snapshot of a collection.
+With Qdrant, the snapshot is stored on the Qdrant server and additionally downloaded when --output is specified.
+With the local store, the collection file is copied to the --output file.`,
+		Args:    cobra.MaximumNArgs(1),
+		RunE:    snapshotCollection,
+		PreRunE: preRunCollection,
+	}
+	collectionRestoreCmd = &cobra.Command{
+		Use:   "restore SNAPSHOT [NAME]",
+		Short: "Restore a collection from a snapshot, replacing its data",
+		Long: `Restore a collection from a snapshot, replacing its data.
+With Qdrant, SNAPSHOT is either a local snapshot file that is uploaded or a URL the Qdrant server downloads the snapshot from.`,
+		Args:    cobra.RangeArgs(1, 2),
+		RunE:    restoreCollection,
+		PreRunE: preRunCollection,
+	}
+	collectionYes      bool
+	snapshotOutputFile string
+)
+
+func init() {
+	storeFactory.AddLLMFlags(collectionCmd.PersistentFlags())
+	storeFactory.AddStoreFlags(collectionCmd.PersistentFlags())
+	collectionDeleteCmd.Flags().BoolVarP(&collectionYes, "yes", "y", collectionYes, "Do not ask for confirmation")
+	collectionRestoreCmd.Flags().BoolVarP(&collectionYes, "yes", "y", collectionYes, "Do not ask for confirmation")
+	collectionSnapshotCmd.Flags().StringVarP(&snapshotOutputFile, "output", "o", snapshotOutputFile, "File to write the snapshot to")
+
+	collectionCmd.AddCommand(collectionListCmd, collectionInfoCmd, collectionCreateCmd, collectionDeleteCmd, collectionSnapshotCmd, collectionRestoreCmd)
+	rootCmd.AddCommand(collectionCmd)
+}
+
+func preRunCollection(cmd *cobra.Command, args []string) error {
+	if storeFactory.Store != "qdrant" && storeFactory.Store != "local" {
+		return fmt.Errorf("unsupported store %q, supported stores are qdrant and local", storeFactory.Store)
+	}
+
+	return nil
+}
+
+func listCollections(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(w, "NAME\tPOINTS\tVECTOR SIZE\tDISTANCE")
+
+	if storeFactory.Store == "local" {
+		names, err := localstore.List(storeFactory.StoreDir)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			count, dimensions, err := localCollectionInfo(localstore.File(storeFactory.StoreDir, name))
+			if err != nil {
+				return err
+			}
+
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%d\tCosine\n", name, count, dimensions)
+		}
+
+		return w.Flush()
+	}
+
+	names, err := qdrantutils.ListCollections(ctx, storeFactory.QdrantURL)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		info, err := qdrantutils.GetCollectionInfo(ctx, storeFactory.QdrantURL, name)
+		if err != nil {
+			return err
+		}
+
+		v := info.Vectors[""]
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", name, info.PointsCount, v.Size, v.Distance)
+	}
+
+	return w.Flush()
+}
+
+func showCollectionInfo(cmd *cobra.Command, args []string) error {
+	setCollectionName(args)
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 1, ' ', 0)
+
+	if storeFactory.Store == "local" {
+		file := storeFactory.localStoreFile()
+
+		count, dimensions, err := localCollectionInfo(file)
+		if err != nil {
+			return err
+		}
+
+		fi, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintf(w, "Name:\t%s\n", storeFactory.QdrantCollection)
+		_, _ = fmt.Fprintf(w, "File:\t%s\n", file)
+		_, _ = fmt.Fprintf(w, "File size:\t%d bytes\n", fi.Size())
+		_, _ = fmt.Fprintf(w, "Points:\t%d\n", count)
+		_, _ = fmt.Fprintf(w, "Vector size:\t%d\n", dimensions)
+		_, _ = fmt.Fprintf(w, "Distance:\tCosine\n")
+
+		return w.Flush()
+	}
+
+	info, err := qdrantutils.GetCollectionInfo(cmd.Context(), storeFactory.QdrantURL, storeFactory.QdrantCollection)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(w, "Name:\t%s\n", info.Name)
+	_, _ = fmt.Fprintf(w, "Status:\t%s\n", info.Status)
+	_, _ = fmt.Fprintf(w, "Points:\t%d\n", info.PointsCount)
+	_, _ = fmt.Fprintf(w, "Indexed vectors:\t%d\n", info.IndexedVectorsCount)
+
+	for _, name := range slices.Sorted(maps.Keys(info.Vectors)) {
+		v := info.Vectors[name]
+		label := "Vector"
+
+		if name != "" {
+			label = fmt.Sprintf("Vector %q", name)
+		}
+
+		_, _ = fmt.Fprintf(w, "%s:\tsize %d, distance %s, datatype %s\n", label, v.Size, v.Distance, orDefault(v.Datatype, "float32"))
+	}
+
+	_, _ = fmt.Fprintf(w, "Sparse vectors:\t%s\n", orDefault(strings.Join(info.SparseVectors, ", "), "none"))
+
+	indexes := make([]string, 0, len(info.PayloadIndexes))
+	for _, field := range slices.Sorted(maps.Keys(info.PayloadIndexes)) {
+		indexes = append(indexes, fmt.Sprintf("%s (%s)", field, info.PayloadIndexes[field]))
+	}
+
+	_, _ = fmt.Fprintf(w, "Payload indexes:\t%s\n", orDefault(strings.Join(indexes, ", "), "none"))
+
+	return w.Flush()
+}
+
+func createCollection(cmd *cobra.Command, args []string) error {
+	setCollectionName(args)
+
+	return storeFactory.CreateCollectionIfNotExist(cmd.Context())
+}
+
+func deleteCollection(cmd *cobra.Command, args []string) error {
+	setCollectionName(args)
+
+	name := storeFactory.QdrantCollection
+
+	ok, err := confirm(cmd, fmt.Sprintf("Delete collection %q along with all of its data?", name))
+	if err != nil || !ok {
+		return err
+	}
+
+	if storeFactory.Store == "local" {
+		err = os.Remove(storeFactory.localStoreFile())
+		if err != nil {
+			return fmt.Errorf("delete local store collection: %w", err)
+		}
+	} else {
+		err = qdrantutils.DeleteCollection(cmd.Context(), storeFactory.QdrantURL, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Deleted collection %s\n", name)
+
+	return nil
+}
+
+func snapshotCollection(cmd *cobra.Command, args []string) error {
+	setCollectionName(args)
+
+	if storeFactory.Store == "local" {
+		if snapshotOutputFile == "" {
+			return errors.New("--output must be specified when using the local store")
+		}
+
+		return copyFile(storeFactory.localStoreFile(), snapshotOutputFile)
+	}
+
+	ctx := cmd.Context()
+	name := storeFactory.QdrantCollection
+
+	snapshot, err := qdrantutils.CreateSnapshot(ctx, storeFactory.QdrantURL, name)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Created snapshot %s of collection %s (%d bytes)\n", snapshot.Name, name, snapshot.Size)
+
+	if snapshotOutputFile == "" {
+		return nil
+	}
+
+	f, err := os.Create(snapshotOutputFile)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	err = qdrantutils.DownloadSnapshot(ctx, storeFactory.QdrantURL, name, snapshot.Name, f)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+func restoreCollection(cmd *cobra.Command, args []string) error {
+	snapshot := args[0]
+
+	setCollectionName(args[1:])
+
+	name := storeFactory.QdrantCollection
+
+	ok, err := confirm(cmd, fmt.Sprintf("Replace the data of collection %q with snapshot %s?", name, snapshot))
+	if err != nil || !ok {
+		return err
+	}
+
+	switch {
+	case storeFactory.Store == "local":
+		err = copyFile(snapshot, storeFactory.localStoreFile())
+	case strings.Contains(snapshot, "://"):
+		err = qdrantutils.RecoverSnapshot(cmd.Context(), storeFactory.QdrantURL, name, snapshot)
+	default:
+		err = qdrantutils.UploadSnapshot(cmd.Context(), storeFactory.QdrantURL, name, snapshot)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Restored collection %s\n", name)
+
+	return nil
+}
+
+// setCollectionName overrides the configured collection with the optional name argument.
+func setCollectionName(args []string) {
+	if len(args) > 0 {
+		storeFactory.QdrantCollection = args[0]
+	}
+}
+
+// confirm asks the user the given question unless --yes was specified.
+func confirm(cmd *cobra.Command, question string) (bool, error) {
+	if collectionYes {
+		return true, nil
+	}
+
+	_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("read confirmation: %w", err)
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "y" && answer != "yes" {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "Aborted")
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func localCollectionInfo(file string) (count, dimensions int, err error) {
+	store, err := localstore.New(file, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	count, err = store.Count()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	dimensions, err = store.Dimensions()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return count, dimensions, nil
+}
+
+// copyFile copies src to dest atomically so that concurrent readers never see a partially written file.
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	err = os.MkdirAll(filepath.Dir(dest), 0o755)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.CreateTemp(filepath.Dir(dest), ".tmp-"+filepath.Base(dest))
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	if err == nil {
+		err = out.Chmod(0o644)
+	}
+
+	if err == nil {
+		err = out.Close()
+	}
+
+	if err == nil {
+		err = os.Rename(out.Name(), dest)
+	}
+
+	if err != nil {
+		return fmt.Errorf("copy %s to %s: %w", src, dest, err)
+	}
+
+	return nil
+}
+
+func orDefault(s, defaultValue string) string {
+	if s == "" {
+		return defaultValue
+	}
+
+	return s
+}
@@ -2,16 +2,23 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 	"net/http"
-	"net/url"
 	"time"
 
+	"github.com/mgoltzsche/knowledgebot/internal/importer"
+	"github.com/mgoltzsche/knowledgebot/internal/localstore"
 	"github.com/mgoltzsche/knowledgebot/internal/qdrantutils"
+	"github.com/mgoltzsche/knowledgebot/internal/qna"
+	"github.com/mgoltzsche/knowledgebot/internal/rerank"
+	"github.com/mgoltzsche/knowledgebot/internal/tokens"
 	"github.com/spf13/pflag"
 	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/openai"
+	"github.com/tmc/langchaingo/schema"
 	"github.com/tmc/langchaingo/vectorstores"
-	"github.com/tmc/langchaingo/vectorstores/qdrant"
 )
 
 type LLMFactory struct {
@@ -40,19 +47,90 @@ func (f *LLMFactory) NewLLM() (*openai.LLM, error) {
 type StoreFactory struct {
 	LLMFactory
 	EmbeddingDimensions int
+	Store               string
+	StoreDir            string
 	QdrantURL           string
 	QdrantCollection    string
+	HybridSearch        bool
+	localStore          *localstore.Store
 }
 
 func (f *StoreFactory) AddStoreFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&f.EmbeddingModel, "embedding-model", f.EmbeddingModel, "Embedding model to use")
-	fs.IntVar(&f.EmbeddingDimensions, "embedding-dimensions", f.EmbeddingDimensions, "LLM embedding model dimensions")
+	fs.IntVar(&f.EmbeddingDimensions, "embedding-dimensions", f.EmbeddingDimensions, "LLM embedding model dimensions (detected by embedding a probe text when 0)")
+	fs.StringVar(&f.Store, "store", f.Store, "Vector store to use (qdrant or local)")
+	fs.StringVar(&f.StoreDir, "store-dir", f.StoreDir, "Directory the local store persists the collections in (requires --store=local)")
 	fs.StringVar(&f.QdrantURL, "qdrant-url", f.QdrantURL, "LLM model to use")
 	fs.StringVar(&f.QdrantCollection, "qdrant-collection", f.QdrantCollection, "LLM model to use")
+	fs.BoolVar(&f.HybridSearch, "hybrid-search", f.HybridSearch, "Index and search keywords in addition to embeddings (requires a collection created with this option)")
 }
 
 func (f *StoreFactory) NewStore() (vectorstores.VectorStore, error) {
-	llm, err := f.NewLLM()
+	switch f.Store {
+	case "qdrant":
+		return f.newQdrantStore(f.EmbeddingModel)
+	case "local":
+		if f.HybridSearch {
+			return nil, fmt.Errorf("hybrid search is not supported by the local store")
+		}
+
+		return f.openLocalStore()
+	default:
+		return nil, fmt.Errorf("unsupported store %q, supported stores are qdrant and local", f.Store)
+	}
+}
+
+// NewStoreCreatingCollection creates the collection unless it exists and returns a store for it.
+// The collection is created before the store is opened since the local store requires its file to exist.
+func (f *StoreFactory) NewStoreCreatingCollection(ctx context.Context) (vectorstores.VectorStore, error) {
+	err := f.CreateCollectionIfNotExist(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.NewStore()
+}
+
+// NewStoreFollowingAlias returns a store that follows the configured Qdrant alias when the configured collection is an alias.
+// It searches the collection the alias points to using the embedding model of that collection,
+// so that the alias can be switched to a collection that was embedded using another model without restarting the server.
+// When the configured collection is not an alias, it returns the configured store.
+func (f *StoreFactory) NewStoreFollowingAlias(ctx context.Context) (vectorstores.VectorStore, error) {
+	if f.Store != "qdrant" {
+		return f.NewStore()
+	}
+
+	target, err := qdrantutils.GetAliasTarget(ctx, f.QdrantURL, f.QdrantCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	if target == "" {
+		return f.NewStore()
+	}
+
+	store, err := qdrantutils.NewAliasStore(ctx, f.QdrantURL, f.QdrantCollection, f.EmbeddingModel, f.newQdrantStoreForCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	if model := store.EmbeddingModel(); model != f.EmbeddingModel {
+		slog.Info(fmt.Sprintf("using embedding model %s of collection %s that qdrant alias %s points to", model, target, f.QdrantCollection))
+		f.EmbeddingModel = model
+	}
+
+	return store, nil
+}
+
+func (f *StoreFactory) newQdrantStore(embeddingModel string) (*qdrantutils.Store, error) {
+	return f.newQdrantStoreForCollection(f.QdrantCollection, embeddingModel)
+}
+
+func (f *StoreFactory) newQdrantStoreForCollection(collection, embeddingModel string) (*qdrantutils.Store, error) {
+	llmFactory := f.LLMFactory
+	llmFactory.EmbeddingModel = embeddingModel
+
+	llm, err := llmFactory.NewLLM()
 	if err != nil {
 		return nil, err
 	}
@@ -62,18 +140,277 @@ func (f *StoreFactory) NewStore() (vectorstores.VectorStore, error) {
 		return nil, err
 	}
 
-	qdrantURL, err := url.Parse(f.QdrantURL)
+	store, err := qdrantutils.NewStore(f.QdrantURL, collection, e, f.HybridSearch)
 	if err != nil {
 		return nil, err
 	}
 
-	return qdrant.New(
-		qdrant.WithURL(*qdrantURL),
-		qdrant.WithCollectionName(f.QdrantCollection),
-		qdrant.WithEmbedder(e),
-	)
+	store.EmbeddingModel = embeddingModel
+
+	return store, nil
 }
 
+func (f *StoreFactory) newEmbedder() (embeddings.Embedder, error) {
+	llm, err := f.NewLLM()
+	if err != nil {
+		return nil, err
+	}
+
+	return embeddings.NewEmbedder(llm)
+}
+
+// CreateCollectionIfNotExist creates the collection unless it exists and verifies that it matches the embedding model.
+// When no embedding dimensions are configured, they are detected by embedding a probe text.
+// The local store does not require the dimensions upfront but checks them when documents are added.
 func (f *StoreFactory) CreateCollectionIfNotExist(ctx context.Context) error {
-	return qdrantutils.CreateQdrantCollectionIfNotExist(ctx, f.QdrantURL, f.QdrantCollection, f.EmbeddingDimensions)
+	if f.Store == "local" {
+		err := localstore.CreateIfNotExist(f.localStoreFile())
+		if err != nil {
+			return err
+		}
+	} else {
+		if f.EmbeddingDimensions <= 0 {
+			err := f.detectEmbeddingDimensions(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := qdrantutils.CreateQdrantCollectionIfNotExist(ctx, f.QdrantURL, f.QdrantCollection, f.EmbeddingDimensions, f.HybridSearch)
+		if err != nil {
+			return err
+		}
+	}
+
+	return f.CheckCollection(ctx)
+}
+
+func (f *StoreFactory) detectEmbeddingDimensions(ctx context.Context) error {
+	e, err := f.newEmbedder()
+	if err != nil {
+		return err
+	}
+
+	vector, err := e.EmbedQuery(ctx, "dimension probe")
+	if err != nil {
+		return fmt.Errorf("detect dimensions of embedding model %q (can be specified using --embedding-dimensions): %w", f.EmbeddingModel, err)
+	}
+
+	f.EmbeddingDimensions = len(vector)
+
+	slog.Debug(fmt.Sprintf("detected %d dimensions of embedding model %s", f.EmbeddingDimensions, f.EmbeddingModel))
+
+	return nil
+}
+
+// CheckCollection returns an error if the collection contains vectors of a different size than the configured embedding dimensions
+// or vectors that were produced by another embedding model.
+// When no embedding dimensions are configured, they are detected by embedding a probe text.
+// This way also collections that were written without embedding model information are checked.
+// A Qdrant collection that does not exist is not considered an error.
+func (f *StoreFactory) CheckCollection(ctx context.Context) error {
+	var (
+		dimensions int
+		models     []string
+	)
+
+	if f.Store == "local" {
+		store, err := f.openLocalStore()
+		if err != nil {
+			return err
+		}
+
+		dimensions, err = store.Dimensions()
+		if err != nil {
+			return err
+		}
+
+		models, err = store.EmbeddingModels()
+		if err != nil {
+			return err
+		}
+	} else {
+		info, err := qdrantutils.GetCollectionInfo(ctx, f.QdrantURL, f.QdrantCollection)
+		if err != nil {
+			if qdrantutils.IsNotFound(err) {
+				slog.Warn(fmt.Sprintf("qdrant collection %s does not exist", f.QdrantCollection))
+				return nil
+			}
+
+			return err
+		}
+
+		dimensions = info.Vectors[""].Size
+
+		model, err := qdrantutils.FindOtherEmbeddingModel(ctx, f.QdrantURL, f.QdrantCollection, f.EmbeddingModel)
+		if err != nil {
+			return err
+		}
+
+		if model != "" {
+			models = []string{model}
+		}
+	}
+
+	if dimensions > 0 && f.EmbeddingDimensions <= 0 {
+		err := f.detectEmbeddingDimensions(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if dimensions > 0 && dimensions != f.EmbeddingDimensions {
+		return fmt.Errorf("collection %s contains vectors of size %d but the embedding model %s produces vectors of size %d, please configure the embedding model the collection was created with or use another collection", f.QdrantCollection, dimensions, f.EmbeddingModel, f.EmbeddingDimensions)
+	}
+
+	for _, model := range models {
+		if model != f.EmbeddingModel {
+			return fmt.Errorf("collection %s contains vectors of the embedding model %s but the embedding model %s is configured, please configure the embedding model the collection was created with or use another collection", f.QdrantCollection, model, f.EmbeddingModel)
+		}
+	}
+
+	return nil
+}
+
+// ScrollDocuments calls fn with batches of the documents stored within the collection, see qdrantutils.ScrollDocuments.
+func (f *StoreFactory) ScrollDocuments(ctx context.Context, batchSize int, withVectors bool, fn func(docs []schema.Document, vectors [][]float32) error) error {
+	if f.Store != "local" {
+		return qdrantutils.ScrollDocuments(ctx, f.QdrantURL, f.QdrantCollection, batchSize, withVectors, fn)
+	}
+
+	store, err := f.openLocalStore()
+	if err != nil {
+		return err
+	}
+
+	return store.ScrollDocuments(batchSize, func(docs []schema.Document, vectors [][]float32) error {
+		if !withVectors {
+			vectors = nil
+		}
+
+		return fn(docs, vectors)
+	})
+}
+
+// openLocalStore opens the local store once so that its file is not loaded repeatedly.
+func (f *StoreFactory) openLocalStore() (*localstore.Store, error) {
+	if f.localStore != nil {
+		return f.localStore, nil
+	}
+
+	e, err := f.newEmbedder()
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := localstore.New(f.localStoreFile(), e)
+	if err != nil {
+		return nil, err
+	}
+
+	store.EmbeddingModel = f.EmbeddingModel
+	f.localStore = store
+
+	return store, nil
+}
+
+func (f *StoreFactory) localStoreFile() string {
+	return localstore.File(f.StoreDir, f.QdrantCollection)
+}
+
+type RerankerFactory struct {
+	Type   string
+	APIURL string
+	APIKey string
+	Model  string
+}
+
+func (f *RerankerFactory) AddRerankerFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&f.Type, "reranker", f.Type, "Reranker used to reorder the retrieved document chunks (none, llm or api)")
+	fs.StringVar(&f.APIURL, "rerank-url", f.APIURL, "URL of the Cohere or Jina compatible rerank API endpoint (requires --reranker=api)")
+	fs.StringVar(&f.APIKey, "rerank-key", f.APIKey, "API key for the rerank API")
+	fs.StringVar(&f.Model, "rerank-model", f.Model, "Model used by the rerank API")
+}
+
+// NewReranker returns the configured reranker or nil when reranking is disabled.
+func (f *RerankerFactory) NewReranker(llm llms.Model) (qna.Reranker, error) {
+	switch f.Type {
+	case "", "none":
+		return nil, nil
+	case "llm":
+		return &rerank.LLMReranker{LLM: llm}, nil
+	case "api":
+		if f.APIURL == "" {
+			return nil, fmt.Errorf("--rerank-url must be specified when using the api reranker")
+		}
+
+		return &rerank.APIReranker{URL: f.APIURL, APIKey: f.APIKey, Model: f.Model}, nil
+	default:
+		return nil, fmt.Errorf("unsupported reranker %q, supported rerankers are none, llm and api", f.Type)
+	}
+}
+
+func addChunkerFlags(fs *pflag.FlagSet, c *importer.Chunker) {
+	fs.IntVar(&c.ChunkSize, "chunk-size", c.ChunkSize, "Chunk size")
+	fs.IntVar(&c.ChunkOverlap, "chunk-overlap", c.ChunkOverlap, "Chunk overlap")
+	fs.Var((*chunkUnitFlag)(c), "chunk-unit", "Unit of the chunk size and overlap (characters or tokens)")
+	fs.IntVar(&c.MaxTokens, "embedding-max-tokens", c.MaxTokens, "Maximum input length of the embedding model in tokens; longer chunks are split (derived from the embedding model by default, -1 disables the check)")
+	fs.BoolVar(&c.SplitByHeading, "split-by-heading", c.SplitByHeading, "Split documents at their headings and store each chunk's heading path and section anchor as metadata")
+	fs.BoolVar(&c.PrependHeadingPath, "prepend-heading-path", c.PrependHeadingPath, "Prepend the heading path to the text of each chunk (requires --split-by-heading)")
+}
+
+// configureChunker derives the embedding model's input limit and warns when the chunk size exceeds it.
+func configureChunker(c *importer.Chunker, embeddingModel string) {
+	if c.MaxTokens == 0 {
+		c.MaxTokens = tokens.ModelMaxTokens(embeddingModel)
+		if c.MaxTokens == 0 {
+			slog.Warn(fmt.Sprintf("unknown input limit of embedding model %q, not checking chunk sizes, see --embedding-max-tokens", embeddingModel))
+		}
+	}
+
+	if c.TokenBased && c.MaxTokens > 0 && c.ChunkSize > c.MaxTokens {
+		slog.Warn(fmt.Sprintf("chunk size of %d tokens exceeds the embedding model's input limit of %d tokens, splitting chunks further", c.ChunkSize, c.MaxTokens))
+	}
+}
+
+func addContentParserFlags(fs *pflag.FlagSet, p *importer.ContentParser) {
+	fs.StringSliceVar(&p.ContentSelectors, "content-selector", p.ContentSelectors, "CSS selector of the HTML element containing the main content")
+	fs.StringSliceVar(&p.ExcludeSelectors, "exclude-selector", p.ExcludeSelectors, "CSS selector of HTML elements to exclude")
+	fs.BoolVar(&p.MainContentDetection, "main-content", p.MainContentDetection, "Detect the main content of HTML documents and remove navigation, headers and footers")
+}
+
+func validateContentParser(p *importer.ContentParser) error {
+	err := importer.ValidateSelectors(p.ContentSelectors)
+	if err != nil {
+		return err
+	}
+
+	return importer.ValidateSelectors(p.ExcludeSelectors)
+}
+
+type chunkUnitFlag importer.Chunker
+
+func (f *chunkUnitFlag) Set(s string) error {
+	switch s {
+	case "characters":
+		f.TokenBased = false
+	case "tokens":
+		f.TokenBased = true
+	default:
+		return fmt.Errorf("unsupported chunk unit %q, supported units are characters and tokens", s)
+	}
+
+	return nil
+}
+
+func (f *chunkUnitFlag) Type() string {
+	return "unit"
+}
+
+func (f *chunkUnitFlag) String() string {
+	if f.TokenBased {
+		return "tokens"
+	}
+
+	return "characters"
 }
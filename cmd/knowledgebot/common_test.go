@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCollectionDetectsDimensionsOfUntaggedCollection(t *testing.T) {
+	qdrant := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/collections/docs":
+			_, _ = w.Write([]byte(`{"result":{"status":"green","points_count":2,"config":{"params":{"vectors":{"size":384,"distance":"Cosine"}}}},"status":"ok"}`))
+		case "/collections/docs/points/scroll":
+			// Points written before the embedding model was stored within the payload.
+			_, _ = w.Write([]byte(`{"result":{"points":[]},"status":"ok"}`))
+		default:
+			t.Errorf("unexpected qdrant request %s", req.URL.Path)
+		}
+	}))
+	defer qdrant.Close()
+
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "/v1/embeddings", req.URL.Path)
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.1,0.2,0.3]}],"model":"nomic-embed-text"}`))
+	}))
+	defer llm.Close()
+
+	f := StoreFactory{
+		LLMFactory:       LLMFactory{APIURL: llm.URL, APIKey: "fake", EmbeddingModel: "nomic-embed-text"},
+		Store:            "qdrant",
+		QdrantURL:        qdrant.URL,
+		QdrantCollection: "docs",
+	}
+
+	err := f.CheckCollection(context.Background())
+	require.ErrorContains(t, err, "contains vectors of size 384 but the embedding model nomic-embed-text produces vectors of size 3")
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"regexp"
+	"time"
 
+	"github.com/mgoltzsche/knowledgebot/internal/importer"
 	"github.com/mgoltzsche/knowledgebot/internal/importer/crawler"
 	"github.com/spf13/cobra"
 )
@@ -17,9 +19,16 @@ var (
 		Args:    cobra.ExactArgs(1),
 	}
 	crawl = crawler.Crawler{
-		MaxDepth:     1,
-		ChunkSize:    768,
-		ChunkOverlap: 175,
+		Chunker: importer.Chunker{
+			ChunkSize:    768,
+			ChunkOverlap: 175,
+		},
+		MaxDepth:         1,
+		RespectRobotsTxt: true,
+		Parallelism:      2,
+		RequestTimeout:   30 * time.Second,
+		MaxRetries:       3,
+		RetryBackoff:     time.Second,
 	}
 )
 
@@ -29,8 +38,19 @@ func init() {
 	f.IntVar(&crawl.MaxDepth, "max-depth", crawl.MaxDepth, "Maximum crawl depth")
 	f.Uint64Var(&crawl.MaxPages, "max-pages", crawl.MaxPages, "Maximum amount of pages to crawl")
 	f.Var((*urlRegexFlag)(&crawl), "url-regex", "regex to filter URLs to crawl")
-	f.IntVar(&crawl.ChunkSize, "chunk-size", crawl.ChunkSize, "Chunk size")
-	f.IntVar(&crawl.ChunkOverlap, "chunk-overlap", crawl.ChunkOverlap, "Chunk overlap")
+	addChunkerFlags(f, &crawl.Chunker)
+	addContentParserFlags(f, &crawl.ContentParser)
+	f.BoolVar(&crawl.Sitemap, "sitemap", crawl.Sitemap, "Seed the crawl with the URLs of the sitemaps listed in robots.txt or /sitemap.xml")
+	f.StringSliceVar(&crawl.SitemapURLs, "sitemap-url", crawl.SitemapURLs, "URL of a sitemap to seed the crawl with")
+	f.StringVar(&crawl.StateFile, "state-file", crawl.StateFile, "File to persist the crawl state in; when set, unchanged pages are skipped and the chunks of changed and removed pages are replaced")
+	f.BoolVar(&crawl.Resume, "resume", crawl.Resume, "Continue the unfinished crawl recorded within the state file instead of starting from the seed URL")
+	f.BoolVar(&crawl.RespectRobotsTxt, "respect-robots-txt", crawl.RespectRobotsTxt, "Honour robots.txt disallow rules and crawl delay")
+	f.IntVar(&crawl.Parallelism, "parallelism", crawl.Parallelism, "Maximum number of concurrent requests")
+	f.DurationVar(&crawl.Delay, "delay", crawl.Delay, "Duration to wait for between requests to the same domain")
+	f.DurationVar(&crawl.RandomDelay, "random-delay", crawl.RandomDelay, "Maximum random duration added to the delay between requests")
+	f.DurationVar(&crawl.RequestTimeout, "request-timeout", crawl.RequestTimeout, "Request timeout")
+	f.IntVar(&crawl.MaxRetries, "max-retries", crawl.MaxRetries, "Maximum number of retries after a 429 or 5xx response or a connection error")
+	f.DurationVar(&crawl.RetryBackoff, "retry-backoff", crawl.RetryBackoff, "Delay before the first retry, doubled with every further attempt (a longer Retry-After response header takes precedence)")
 	storeFactory.AddLLMFlags(f)
 	storeFactory.AddStoreFlags(f)
 
@@ -38,7 +58,14 @@ func init() {
 }
 
 func preRunCrawl(cmd *cobra.Command, args []string) error {
-	store, err := storeFactory.NewStore()
+	configureChunker(&crawl.Chunker, storeFactory.EmbeddingModel)
+
+	err := validateContentParser(&crawl.ContentParser)
+	if err != nil {
+		return err
+	}
+
+	store, err := storeFactory.NewStoreCreatingCollection(cmd.Context())
 	if err != nil {
 		return err
 	}
@@ -49,11 +76,6 @@ func preRunCrawl(cmd *cobra.Command, args []string) error {
 }
 
 func crawlWebsite(cmd *cobra.Command, args []string) error {
-	err := storeFactory.CreateCollectionIfNotExist(cmd.Context())
-	if err != nil {
-		return err
-	}
-
 	return crawl.Crawl(cmd.Context(), args[0])
 }
 
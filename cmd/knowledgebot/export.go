@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/mgoltzsche/knowledgebot/internal/importer/jsonl"
+	"github.com/spf13/cobra"
+	"github.com/tmc/langchaingo/schema"
+)
+
+var (
+	exportCmd = &cobra.Command{
+		Use:   "export [FILE]",
+		Short: "Export the documents of a collection as JSON Lines",
+		Long: `Export the document chunks of the configured collection along with their metadata as JSON Lines.
+The documents are written to stdout unless a file is specified.
+The export can be imported using the "import jsonl" command.`,
+		Args:    cobra.MaximumNArgs(1),
+		RunE:    runExport,
+		PreRunE: preRunCollection,
+	}
+	exportWithVectors bool
+)
+
+func init() {
+	f := exportCmd.Flags()
+
+	f.BoolVar(&exportWithVectors, "with-vectors", exportWithVectors, "Export the vectors as well so that they do not need to be computed again when importing the documents using the same embedding model")
+	storeFactory.AddLLMFlags(f)
+	storeFactory.AddStoreFlags(f)
+
+	rootCmd.AddCommand(exportCmd)
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	var file *os.File
+
+	out := cmd.OutOrStdout()
+
+	if len(args) > 0 && args[0] != "-" {
+		f, err := os.Create(args[0])
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		file = f
+		out = f
+	}
+
+	w := bufio.NewWriter(out)
+	enc := jsonl.NewEncoder(w)
+	count := 0
+
+	err := storeFactory.ScrollDocuments(cmd.Context(), 256, exportWithVectors, func(docs []schema.Document, vectors [][]float32) error {
+		count += len(docs)
+		return enc.Encode(docs, vectors)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = w.Flush()
+	if err == nil && file != nil {
+		err = file.Close()
+	}
+
+	if err != nil {
+		return fmt.Errorf("write export: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("exported %d documents of collection %s", count, storeFactory.QdrantCollection))
+
+	return nil
+}
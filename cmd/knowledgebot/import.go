@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/url"
+	"os"
+
+	"github.com/mgoltzsche/knowledgebot/internal/importer"
+	"github.com/mgoltzsche/knowledgebot/internal/importer/directory"
+	"github.com/mgoltzsche/knowledgebot/internal/importer/jsonl"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importCmd = &cobra.Command{
+		Use:   "import",
+		Short: "Import documents from a local source",
+		Long:  `Import documents from a local source.`,
+	}
+	importDirCmd = &cobra.Command{
+		Use:   "dir PATH",
+		Short: "Import the files within a local directory",
+		Long: `Import the Markdown, HTML and plain text files within a local directory.
+YAML front matter of Markdown files is added to the chunk metadata.`,
+		RunE:    importDirectory,
+		PreRunE: preRunImportDir,
+		Args:    cobra.ExactArgs(1),
+	}
+	dirImporter = directory.Importer{
+		Chunker: importer.Chunker{
+			ChunkSize:    768,
+			ChunkOverlap: 175,
+		},
+	}
+	importBaseURL  string
+	importJSONLCmd = &cobra.Command{
+		Use:   "jsonl FILE",
+		Short: "Import the documents of a JSON Lines export",
+		Long: `Import the documents of a JSON Lines file that was written by the export command ("-" reads stdin).
+Exported vectors are reused when they were produced by the configured embedding model, all other documents are embedded.`,
+		RunE:    importJSONL,
+		PreRunE: preRunImportJSONL,
+		Args:    cobra.ExactArgs(1),
+	}
+	jsonlImporter = jsonl.Importer{
+		BatchSize: 64,
+	}
+)
+
+func init() {
+	f := importDirCmd.Flags()
+
+	f.StringVar(&importBaseURL, "base-url", importBaseURL, "URL the relative file paths are resolved against to derive the document URLs (file:// URLs by default)")
+	addChunkerFlags(f, &dirImporter.Chunker)
+	addContentParserFlags(f, &dirImporter.ContentParser)
+	storeFactory.AddLLMFlags(f)
+	storeFactory.AddStoreFlags(f)
+
+	f = importJSONLCmd.Flags()
+
+	f.IntVar(&jsonlImporter.BatchSize, "batch-size", jsonlImporter.BatchSize, "Number of documents to add to the store at once")
+	storeFactory.AddLLMFlags(f)
+	storeFactory.AddStoreFlags(f)
+
+	importCmd.AddCommand(importDirCmd, importJSONLCmd)
+	rootCmd.AddCommand(importCmd)
+}
+
+func preRunImportDir(cmd *cobra.Command, args []string) error {
+	configureChunker(&dirImporter.Chunker, storeFactory.EmbeddingModel)
+
+	err := validateContentParser(&dirImporter.ContentParser)
+	if err != nil {
+		return err
+	}
+
+	if importBaseURL != "" {
+		u, err := url.Parse(importBaseURL)
+		if err != nil {
+			return err
+		}
+
+		dirImporter.BaseURL = u
+	}
+
+	store, err := storeFactory.NewStoreCreatingCollection(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	dirImporter.Sink = store
+
+	return nil
+}
+
+func importDirectory(cmd *cobra.Command, args []string) error {
+	return dirImporter.Import(cmd.Context(), args[0])
+}
+
+func preRunImportJSONL(cmd *cobra.Command, args []string) error {
+	store, err := storeFactory.NewStoreCreatingCollection(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	jsonlImporter.Sink = store
+	jsonlImporter.EmbeddingModel = storeFactory.EmbeddingModel
+
+	return nil
+}
+
+func importJSONL(cmd *cobra.Command, args []string) error {
+	in := cmd.InOrStdin()
+
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		in = f
+	}
+
+	return jsonlImporter.Import(cmd.Context(), in)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+
+	"github.com/mgoltzsche/knowledgebot/internal/mcp"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mcpCmd = &cobra.Command{
+		Use:   "mcp",
+		Short: "Run a Model Context Protocol server",
+		Long: `Run a Model Context Protocol (MCP) server that exposes the knowledge base to AI assistants.
+It provides the tools search_knowledge and ask_knowledgebot.`,
+		Args:    cobra.ExactArgs(0),
+		RunE:    runMCPServer,
+		PreRunE: preRunMCPServer,
+	}
+	mcpTransport  = "stdio"
+	mcpListenAddr = ":8080"
+)
+
+func init() {
+	f := mcpCmd.Flags()
+
+	f.StringVar(&mcpTransport, "transport", mcpTransport, "MCP transport to use (stdio or http)")
+	f.StringVar(&mcpListenAddr, "listen", mcpListenAddr, "Address the server should listen on when using the http transport")
+	addWorkflowFlags(f)
+
+	rootCmd.AddCommand(mcpCmd)
+}
+
+func preRunMCPServer(cmd *cobra.Command, args []string) error {
+	if mcpTransport != "stdio" && mcpTransport != "http" {
+		return fmt.Errorf("unsupported transport %q, supported transports are stdio and http", mcpTransport)
+	}
+
+	return setupWorkflow(cmd.Context())
+}
+
+func runMCPServer(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	srv := &mcp.Server{Name: "knowledgebot", Version: version()}
+
+	mcp.AddKnowledgeTools(srv, workflow)
+
+	if mcpTransport == "stdio" {
+		return srv.ServeStdio(ctx, os.Stdin, os.Stdout)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", srv.HTTPHandler())
+
+	httpSrv := &http.Server{
+		Addr:        mcpListenAddr,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+		Handler:     mux,
+	}
+
+	go func() {
+		<-ctx.Done()
+		err := httpSrv.Shutdown(ctx)
+		if err != nil {
+			slog.Error("failed to shutdown server: " + err.Error())
+		}
+	}()
+
+	slog.Info("mcp server listening on " + httpSrv.Addr + "/mcp")
+
+	err := httpSrv.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+
+	return err
+}
+
+// version returns the module version the binary was built from.
+func version() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+
+	return "dev"
+}
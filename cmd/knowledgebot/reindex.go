@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/mgoltzsche/knowledgebot/internal/qdrantutils"
+	"github.com/spf13/cobra"
+	"github.com/tmc/langchaingo/schema"
+)
+
+var (
+	reindexCmd = &cobra.Command{
+		Use:   "reindex",
+		Short: "Re-embed the documents of a collection into a new collection",
+		Long: `Re-embed the documents of a Qdrant collection into a new collection using the configured embedding model.
+The chunk texts are read from the source collection, so the sources do not need to be crawled again.
+Optionally, an alias is switched to the new collection afterwards, e.g. the collection the server uses.`,
+		Args:    cobra.ExactArgs(0),
+		RunE:    runReindex,
+		PreRunE: preRunReindex,
+	}
+	reindexFromCollection string
+	reindexToCollection   string
+	reindexAlias          string
+	reindexBatchSize      = 64
+)
+
+func init() {
+	f := reindexCmd.Flags()
+
+	f.StringVar(&reindexFromCollection, "from-collection", reindexFromCollection, "Collection to read the documents from (defaults to --qdrant-collection)")
+	f.StringVar(&reindexToCollection, "to-collection", reindexToCollection, "Collection to write the re-embedded documents to")
+	f.StringVar(&reindexAlias, "alias", reindexAlias, "Qdrant alias to point to the new collection after the documents were re-embedded")
+	f.IntVar(&reindexBatchSize, "batch-size", reindexBatchSize, "Number of documents to embed at once")
+	storeFactory.AddLLMFlags(f)
+	storeFactory.AddStoreFlags(f)
+
+	rootCmd.AddCommand(reindexCmd)
+}
+
+func preRunReindex(cmd *cobra.Command, args []string) error {
+	if storeFactory.Store != "qdrant" {
+		return errors.New("reindex requires --store=qdrant")
+	}
+
+	if reindexFromCollection == "" {
+		reindexFromCollection = storeFactory.QdrantCollection
+	}
+
+	if reindexToCollection == "" {
+		return errors.New("--to-collection must be specified")
+	}
+
+	if reindexToCollection == reindexFromCollection {
+		return errors.New("--to-collection must differ from the source collection")
+	}
+
+	if reindexAlias == reindexToCollection {
+		return errors.New("--alias must differ from the collection names")
+	}
+
+	if reindexBatchSize < 1 {
+		return errors.New("--batch-size must be at least 1")
+	}
+
+	if reindexAlias != "" {
+		// Qdrant cannot create an alias with the name of an existing collection.
+		collections, err := qdrantutils.ListCollections(cmd.Context(), storeFactory.QdrantURL)
+		if err != nil {
+			return err
+		}
+
+		if slices.Contains(collections, reindexAlias) {
+			return fmt.Errorf("--alias %s refers to an existing collection: move it to another collection using collection snapshot and collection restore and delete it first", reindexAlias)
+		}
+	}
+
+	return nil
+}
+
+func runReindex(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	storeFactory.QdrantCollection = reindexToCollection
+
+	store, err := storeFactory.NewStoreCreatingCollection(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.Info(fmt.Sprintf("re-embedding the documents of collection %s into collection %s using embedding model %s", reindexFromCollection, reindexToCollection, storeFactory.EmbeddingModel))
+
+	start := time.Now()
+	count := 0
+
+	err = qdrantutils.ScrollDocuments(ctx, storeFactory.QdrantURL, reindexFromCollection, reindexBatchSize, false, func(docs []schema.Document, _ [][]float32) error {
+		_, err := store.AddDocuments(ctx, docs)
+		if err != nil {
+			return err
+		}
+
+		count += len(docs)
+
+		slog.Info(fmt.Sprintf("re-embedded %d documents", count))
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info(fmt.Sprintf("re-embedded %d documents in %s", count, time.Since(start)))
+
+	if reindexAlias != "" {
+		err = qdrantutils.SetAlias(ctx, storeFactory.QdrantURL, reindexAlias, reindexToCollection)
+		if err != nil {
+			return err
+		}
+
+		slog.Info(fmt.Sprintf("qdrant alias %s points to collection %s now", reindexAlias, reindexToCollection))
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"github.com/mgoltzsche/knowledgebot/internal/qna"
 	"github.com/mgoltzsche/knowledgebot/internal/server"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
@@ -20,12 +22,19 @@ var (
 		RunE:    runServer,
 		PreRunE: preRunServer,
 	}
-	listenAddr = ":8080"
-	workflow   = &qna.QuestionAnswerWorkflow{
-		Temperature:    0.7,
-		MaxDocs:        15,
-		ScoreThreshold: 0.5,
-		Topic:          "The TV show Futurama",
+	listenAddr         = ":8080"
+	promptTemplateFile string
+	workflow           = &qna.QuestionAnswerWorkflow{
+		Temperature:      0.7,
+		MaxDocs:          15,
+		ScoreThreshold:   0.5,
+		Topic:            "The TV show Futurama",
+		MaxDocsLimit:     50,
+		MaxTemperature:   1.5,
+		MaxHistory:       10,
+		RerankCandidates: 50,
+		ContextTokens:    4096,
+		AnswerTokens:     1024,
 	}
 	routes = server.Routes{
 		WebDir:   "/var/lib/knowledgebot/ui",
@@ -36,6 +45,9 @@ var (
 		APIKey: "ollama",
 		Model:  "qwen2.5:3b",
 	}
+	rerankerFactory = RerankerFactory{
+		Type: "none",
+	}
 	storeFactory = StoreFactory{
 		LLMFactory: LLMFactory{
 			APIURL: llmFactory.APIURL,
@@ -44,9 +56,10 @@ var (
 			//EmbeddingModel: "nomic-embed-text",
 			EmbeddingModel: "all-minilm",
 		},
-		EmbeddingDimensions: 384,
-		QdrantURL:           "http://qdrant:6333",
-		QdrantCollection:    "knowledgebot",
+		Store:            "qdrant",
+		StoreDir:         "data",
+		QdrantURL:        "http://qdrant:6333",
+		QdrantCollection: "knowledgebot",
 	}
 )
 
@@ -55,22 +68,61 @@ func init() {
 
 	f.StringVar(&listenAddr, "listen", listenAddr, "Address the server should listen on")
 	f.StringVar(&routes.WebDir, "web-dir", routes.WebDir, "Path to the web UI directory")
+	addWorkflowFlags(f)
+
+	rootCmd.AddCommand(serveCmd)
+}
+
+// addWorkflowFlags adds the flags that configure the question answering workflow.
+func addWorkflowFlags(f *pflag.FlagSet) {
 	f.StringVar(&workflow.Topic, "topic", workflow.Topic, "The topic used in the promtTemplate")
+	f.StringVar(&promptTemplateFile, "prompt-template", promptTemplateFile, "Path to a file containing the system prompt template (Go template syntax)")
+	f.StringToStringVar(&workflow.PromptVars, "prompt-var", workflow.PromptVars, "Custom variable passed to the prompt template as .Vars.<key> (key=value)")
 	f.Float64Var(&workflow.Temperature, "temperature", workflow.Temperature, "LLM temperature")
 	f.IntVar(&workflow.MaxDocs, "max-docs", workflow.MaxDocs, "Maximum number of document chunks to retrieve from qdrant")
 	f.Float64Var(&workflow.ScoreThreshold, "score-threshold", workflow.ScoreThreshold, "qdrant lookup score threshold")
+	f.IntVar(&workflow.MaxDocsLimit, "max-docs-limit", workflow.MaxDocsLimit, "Maximum number of document chunks a request can ask for (0 for unlimited)")
+	f.Float64Var(&workflow.MaxTemperature, "max-temperature", workflow.MaxTemperature, "Maximum LLM temperature a request can ask for (0 for unlimited)")
+	f.IntVar(&workflow.ContextTokens, "context-tokens", workflow.ContextTokens, "Context window size of the LLM in tokens; retrieved chunks that do not fit are dropped (0 disables the check)")
+	f.IntVar(&workflow.AnswerTokens, "answer-tokens", workflow.AnswerTokens, "Number of tokens reserved for the answer within the context window")
+	f.IntVar(&workflow.MaxHistory, "max-history", workflow.MaxHistory, "Maximum number of previous conversation messages passed to the LLM (-1 for unlimited)")
+	f.IntVar(&workflow.RerankCandidates, "rerank-candidates", workflow.RerankCandidates, "Number of document chunks to retrieve for the reranker to select the top --max-docs from")
 	llmFactory.AddLLMFlags(f)
+	rerankerFactory.AddRerankerFlags(f)
 	storeFactory.AddStoreFlags(f)
-
-	rootCmd.AddCommand(serveCmd)
 }
 
 func preRunServer(cmd *cobra.Command, args []string) error {
+	return setupWorkflow(cmd.Context())
+}
+
+// setupWorkflow validates the prompt template and initializes the LLM, vector store and reranker of the workflow.
+// It fails when the collection was filled using another embedding model.
+func setupWorkflow(ctx context.Context) error {
+	if promptTemplateFile != "" {
+		tmpl, err := qna.LoadPromptTemplate(promptTemplateFile)
+		if err != nil {
+			return err
+		}
+
+		err = tmpl.Validate(workflow.Topic, workflow.PromptVars)
+		if err != nil {
+			return fmt.Errorf("invalid prompt template %s: %w", promptTemplateFile, err)
+		}
+
+		workflow.PromptTemplate = tmpl
+	}
+
 	embeddingsModel := storeFactory.EmbeddingModel
 	storeFactory.LLMFactory = llmFactory
 	storeFactory.EmbeddingModel = embeddingsModel
 
-	store, err := storeFactory.NewStore()
+	store, err := storeFactory.NewStoreFollowingAlias(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = storeFactory.CheckCollection(ctx)
 	if err != nil {
 		return err
 	}
@@ -80,8 +132,15 @@ func preRunServer(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	routes.Workflow.Store = store
-	routes.Workflow.LLM = llm
+	reranker, err := rerankerFactory.NewReranker(llm)
+	if err != nil {
+		return err
+	}
+
+	workflow.Store = store
+	workflow.LLM = llm
+	workflow.HybridSearch = storeFactory.HybridSearch
+	workflow.Reranker = reranker
 
 	return nil
 }
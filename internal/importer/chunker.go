@@ -0,0 +1,314 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/mgoltzsche/knowledgebot/internal/tokens"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/textsplitter"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// DocumentDeleter is implemented by vector stores that can delete the previously indexed chunks of a document.
+type DocumentDeleter interface {
+	DeleteDocumentsByURL(ctx context.Context, url string) error
+}
+
+// StaleDocumentDeleter is implemented by vector stores that can delete the previously indexed chunks of a document except the given ones.
+// This allows to add the current chunks of a document before deleting the stale ones, without embedding unchanged chunks again.
+type StaleDocumentDeleter interface {
+	DeleteDocumentsByURLExcept(ctx context.Context, url string, keepIDs []string) error
+}
+
+// ReplaceDocuments replaces the previously indexed chunks of the given URL within the store with the given chunks.
+// When supported by the store, the chunks are added before the stale ones are deleted,
+// so that unchanged chunks are not embedded again and the previous chunks remain when adding fails.
+func ReplaceDocuments(ctx context.Context, store vectorstores.VectorStore, u string, chunks []schema.Document) error {
+	if deleter, ok := store.(StaleDocumentDeleter); ok {
+		var ids []string
+
+		if len(chunks) > 0 {
+			var err error
+
+			ids, err = store.AddDocuments(ctx, chunks)
+			if err != nil {
+				return err
+			}
+		}
+
+		return deleter.DeleteDocumentsByURLExcept(ctx, u, ids)
+	}
+
+	if deleter, ok := store.(DocumentDeleter); ok {
+		err := deleter.DeleteDocumentsByURL(ctx, u)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(chunks) > 0 {
+		_, err := store.AddDocuments(ctx, chunks)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Chunker splits markdown documents into chunks, skipping chunks that it has emitted before.
+type Chunker struct {
+	ChunkSize    int
+	ChunkOverlap int
+	// TokenBased makes ChunkSize and ChunkOverlap specify an estimated number of tokens instead of characters.
+	TokenBased bool
+	// MaxTokens is the embedding model's maximum input length in tokens.
+	// Chunks that are estimated to exceed it are split further. Zero disables the check.
+	MaxTokens int
+	// SplitByHeading makes chunks not span multiple sections and records the heading path of a chunk's section
+	// within the "section" metadata key and the section's anchor within the "anchor" metadata key.
+	SplitByHeading bool
+	// PrependHeadingPath prepends the heading path to the text of each chunk when SplitByHeading is enabled.
+	PrependHeadingPath bool
+	mutex              sync.Mutex
+	knownChunkHashes   map[string]struct{}
+}
+
+// Chunk splits the given markdown into chunks that each carry a copy of the given metadata.
+func (c *Chunker) Chunk(markdown string, metadata map[string]any) ([]schema.Document, error) {
+	return c.chunk(markdown, nil, metadata)
+}
+
+func (c *Chunker) chunk(markdown string, anchors map[string]string, metadata map[string]any) ([]schema.Document, error) {
+	if !c.SplitByHeading {
+		return c.split(markdown, "", metadata)
+	}
+
+	var docs []schema.Document
+
+	for _, s := range splitSections(markdown, anchors) {
+		sectionMetadata := copyMetadata(metadata, 2)
+		path := s.headingPath()
+		prefix := ""
+
+		if path != "" {
+			sectionMetadata["section"] = path
+			sectionMetadata["anchor"] = s.anchor
+
+			if c.PrependHeadingPath {
+				prefix = path + "\n\n"
+			}
+		}
+
+		chunks, err := c.split(s.text, prefix, sectionMetadata)
+		if err != nil {
+			return nil, err
+		}
+
+		docs = append(docs, chunks...)
+	}
+
+	return docs, nil
+}
+
+func (c *Chunker) split(markdown, prefix string, metadata map[string]any) ([]schema.Document, error) {
+	opts := []textsplitter.Option{
+		textsplitter.WithChunkSize(c.ChunkSize),
+		textsplitter.WithChunkOverlap(c.ChunkOverlap),
+	}
+
+	if c.TokenBased {
+		opts = append(opts, textsplitter.WithLenFunc(tokens.Estimate))
+	}
+
+	chunks, err := textsplitter.NewMarkdownTextSplitter(opts...).SplitText(markdown)
+	if err != nil {
+		return nil, fmt.Errorf("split text: %w", err)
+	}
+
+	chunks, err = c.splitOversizedChunks(chunks, tokens.Estimate(prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]schema.Document, 0, len(chunks))
+
+	for _, chunk := range chunks {
+		chunk = prefix + chunk
+
+		if c.knownChunk(chunk) {
+			continue
+		}
+
+		docs = append(docs, schema.Document{
+			PageContent: chunk,
+			Metadata:    copyMetadata(metadata, 0),
+		})
+	}
+
+	return docs, nil
+}
+
+// splitOversizedChunks splits the chunks that would be truncated by the embedding model into smaller ones.
+// The given number of tokens is reserved for a prefix.
+func (c *Chunker) splitOversizedChunks(chunks []string, reservedTokens int) ([]string, error) {
+	if c.MaxTokens <= 0 {
+		return chunks, nil
+	}
+
+	// Don't let a long heading path take up the space of the chunk's actual content.
+	maxTokens := max(c.MaxTokens-reservedTokens, c.MaxTokens/2)
+	splitter := textsplitter.NewRecursiveCharacter(
+		textsplitter.WithChunkSize(maxTokens),
+		textsplitter.WithChunkOverlap(0),
+		textsplitter.WithLenFunc(tokens.Estimate),
+	)
+	result := make([]string, 0, len(chunks))
+
+	for _, chunk := range chunks {
+		tokenCount := tokens.Estimate(chunk)
+		if tokenCount <= maxTokens {
+			result = append(result, chunk)
+			continue
+		}
+
+		split, err := splitter.SplitText(chunk)
+		if err != nil {
+			return nil, fmt.Errorf("split oversized chunk: %w", err)
+		}
+
+		slog.Debug(fmt.Sprintf("split chunk of ~%d tokens into %d chunks to fit the embedding model's input limit of %d tokens", tokenCount, len(split), c.MaxTokens))
+
+		result = append(result, split...)
+	}
+
+	return result, nil
+}
+
+// ChunkContent splits the given document content into chunks.
+// The chunks of paginated documents carry the number of the page they originate from within the "page" metadata key.
+func (c *Chunker) ChunkContent(content *Content, metadata map[string]any) ([]schema.Document, error) {
+	if len(content.Pages) == 0 {
+		return c.chunk(content.Markdown, content.Anchors, metadata)
+	}
+
+	docs := make([]schema.Document, 0, len(content.Pages))
+
+	for i, page := range content.Pages {
+		pageMetadata := copyMetadata(metadata, 1)
+		pageMetadata["page"] = i + 1
+
+		chunks, err := c.Chunk(page, pageMetadata)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", i+1, err)
+		}
+
+		docs = append(docs, chunks...)
+	}
+
+	return docs, nil
+}
+
+// copyMetadata returns a copy of the given metadata with room for the given number of additional keys.
+// Unlike maps.Clone, it returns a writable map when the metadata is nil.
+func copyMetadata(metadata map[string]any, additionalKeys int) map[string]any {
+	m := make(map[string]any, len(metadata)+additionalKeys)
+	maps.Copy(m, metadata)
+
+	return m
+}
+
+// AddKnownChunkHashes makes the chunker skip chunks with the given hashes, e.g. chunks that were indexed by a previous run.
+func (c *Chunker) AddKnownChunkHashes(hashes []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.knownChunkHashes == nil {
+		c.knownChunkHashes = make(map[string]struct{}, len(hashes))
+	}
+
+	for _, h := range hashes {
+		c.knownChunkHashes[h] = struct{}{}
+	}
+}
+
+func (c *Chunker) knownChunk(chunk string) bool {
+	key := HashString(chunk)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.knownChunkHashes == nil {
+		c.knownChunkHashes = map[string]struct{}{}
+	}
+
+	_, known := c.knownChunkHashes[key]
+	if !known {
+		c.knownChunkHashes[key] = struct{}{}
+	}
+
+	return known
+}
+
+// HashString returns the hex-encoded SHA256 hash of the given string.
+func HashString(s string) string {
+	h := sha256.New()
+	_, _ = h.Write([]byte(s))
+	b := h.Sum(nil)
+
+	return hex.EncodeToString(b)
+}
+
+// EqualMetadata returns true if the given document metadata is equal when encoded as JSON, ignoring differing Go number types.
+func EqualMetadata(a, b map[string]any) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+
+	return errA == nil && errB == nil && bytes.Equal(aJSON, bJSON)
+}
+
+// DeriveTitle returns the document's first H1 heading or the last URL path segment, suffixed with the URL's host.
+func DeriveTitle(markdown string, u *url.URL) string {
+	return withHostname(deriveTitle(markdown, u), u)
+}
+
+func deriveTitle(markdown string, u *url.URL) string {
+	for _, line := range strings.Split(markdown, "\n") {
+		if strings.HasPrefix(line, "# ") {
+			title := strings.Trim(line[2:], "*_ ")
+			if len(title) > 0 {
+				return title
+			}
+		}
+	}
+
+	pathSegments := strings.Split(u.Path, "/")
+	lastPathSegment := pathSegments[len(pathSegments)-1]
+
+	if lastPathSegment == "" || lastPathSegment == "." && len(pathSegments) > 1 {
+		lastPathSegment = pathSegments[len(pathSegments)-2]
+		if lastPathSegment == "" || lastPathSegment == "." {
+			lastPathSegment = u.Path
+		}
+	}
+
+	return lastPathSegment
+}
+
+func withHostname(title string, u *url.URL) string {
+	if u.Hostname() == "" {
+		return title
+	}
+
+	return fmt.Sprintf("%s | %s", title, u.Hostname())
+}
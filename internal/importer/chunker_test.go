@@ -0,0 +1,109 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mgoltzsche/knowledgebot/internal/tokens"
+	"github.com/stretchr/testify/require"
+)
+
+const testSectionsMarkdown = `Intro text.
+
+# Futurama
+
+An animated series.
+
+## Episodes
+
+` + "```" + `
+# not a heading
+` + "```" + `
+
+### Season 1 *(1999)*
+
+Space Pilot 3000.
+
+## Cast
+
+Billy West.`
+
+func TestChunkerSplitByHeading(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		chunker *Chunker
+		anchors map[string]string
+		expect  []string
+	}{
+		{
+			name:    "section metadata",
+			chunker: &Chunker{ChunkSize: 500, SplitByHeading: true},
+			anchors: map[string]string{"Season 1 (1999)": "Season_1"},
+			expect: []string{
+				"||Intro text.",
+				"Futurama|futurama|# Futurama\nAn animated series.",
+				"Futurama > Episodes|episodes|## Episodes",
+				"Futurama > Episodes > Season 1 (1999)|Season_1|### Season 1 *(1999)*\nSpace Pilot 3000.",
+				"Futurama > Cast|cast|## Cast\nBilly West.",
+			},
+		},
+		{
+			name:    "prepend heading path",
+			chunker: &Chunker{ChunkSize: 500, SplitByHeading: true, PrependHeadingPath: true},
+			expect: []string{
+				"||Intro text.",
+				"Futurama|futurama|Futurama\n\n# Futurama\nAn animated series.",
+				"Futurama > Episodes|episodes|Futurama > Episodes\n\n## Episodes",
+				"Futurama > Episodes > Season 1 (1999)|season-1-1999|Futurama > Episodes > Season 1 (1999)\n\n### Season 1 *(1999)*\nSpace Pilot 3000.",
+				"Futurama > Cast|cast|Futurama > Cast\n\n## Cast\nBilly West.",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			docs, err := tc.chunker.ChunkContent(&Content{Markdown: testSectionsMarkdown, Anchors: tc.anchors}, map[string]any{"url": "https://example.org"})
+			require.NoError(t, err)
+
+			actual := make([]string, len(docs))
+			for i, doc := range docs {
+				section, _ := doc.Metadata["section"].(string)
+				anchor, _ := doc.Metadata["anchor"].(string)
+				actual[i] = section + "|" + anchor + "|" + doc.PageContent
+				require.Equal(t, "https://example.org", doc.Metadata["url"])
+			}
+
+			require.Equal(t, tc.expect, actual)
+		})
+	}
+}
+
+func TestChunkerMaxTokens(t *testing.T) {
+	text := strings.Repeat("Bender is a robot. ", 40)
+
+	for _, tc := range []struct {
+		name    string
+		chunker *Chunker
+	}{
+		{"characters", &Chunker{ChunkSize: 768, MaxTokens: 64}},
+		{"tokens", &Chunker{ChunkSize: 300, TokenBased: true, MaxTokens: 64}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			docs, err := tc.chunker.Chunk(text, map[string]any{})
+			require.NoError(t, err)
+			require.Greater(t, len(docs), 1)
+
+			for _, doc := range docs {
+				require.LessOrEqual(t, tokens.Estimate(doc.PageContent), 64, doc.PageContent)
+			}
+		})
+	}
+}
+
+func TestChunkerNilMetadata(t *testing.T) {
+	c := &Chunker{ChunkSize: 500, SplitByHeading: true}
+
+	docs, err := c.ChunkContent(&Content{Pages: []string{testSectionsMarkdown}}, nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, docs)
+	require.Equal(t, 1, docs[0].Metadata["page"])
+	require.Equal(t, "Futurama", docs[1].Metadata["section"])
+}
@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
+)
+
+// ErrUnsupportedContentType is returned when a document's content type cannot be imported.
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// Content is the text of a document.
+type Content struct {
+	// Title is the title specified within the document's metadata, if any.
+	Title string
+	// Markdown is the document's text.
+	Markdown string
+	// Pages holds the text of each page for paginated documents such as PDFs.
+	Pages []string
+	// Anchors maps the titles of the document's headings to the IDs of the corresponding HTML elements.
+	Anchors map[string]string
+}
+
+// ContentParser converts documents into text.
+type ContentParser struct {
+	// ContentSelectors are CSS selectors that select the main content of HTML documents.
+	// The first selector that matches any element is used.
+	ContentSelectors []string
+	// ExcludeSelectors are CSS selectors that select elements to remove from HTML documents.
+	ExcludeSelectors []string
+	// MainContentDetection enables the heuristic detection of an HTML document's main content
+	// and the removal of boilerplate such as navigation, headers, footers and cookie banners.
+	MainContentDetection bool
+}
+
+// Parse converts a document of the given content type into text.
+// When the content type is empty, it is detected from the document's body.
+func (p *ContentParser) Parse(contentType string, body []byte) (*Content, error) {
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("parse content type: %w", err)
+	}
+
+	switch mediaType {
+	case "text/html", "application/xhtml+xml":
+		return p.parseHTML(contentType, body)
+	case "text/markdown", "text/x-markdown", "text/plain":
+		return &Content{Markdown: StripMarkdownLinks(string(body))}, nil
+	case "application/pdf":
+		return parsePDF(body)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedContentType, mediaType)
+	}
+}
+
+func (p *ContentParser) parseHTML(contentType string, body []byte) (*Content, error) {
+	html, h1, anchors, err := p.extractHTMLContent(contentType, body)
+	if err != nil {
+		return nil, err
+	}
+
+	markdown, err := htmltomarkdown.ConvertString(html)
+	if err != nil {
+		return nil, fmt.Errorf("html to markdown: %w", err)
+	}
+
+	return &Content{
+		Title:    h1,
+		Markdown: StripMarkdownLinks(markdown),
+		Anchors:  anchors,
+	}, nil
+}
+
+// Text returns the document's whole text.
+func (c *Content) Text() string {
+	if len(c.Pages) > 0 {
+		return strings.Join(c.Pages, "\n\n")
+	}
+
+	return c.Markdown
+}
+
+// DeriveTitle returns the title specified within the document's metadata or derives one from its text and the given URL.
+func (c *Content) DeriveTitle(u *url.URL) string {
+	if c.Title != "" {
+		return withHostname(c.Title, u)
+	}
+
+	return DeriveTitle(c.Markdown, u)
+}
@@ -0,0 +1,105 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseContent(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		input       []byte
+		expect      Content
+	}{
+		{
+			name:        "html",
+			contentType: "text/html; charset=utf-8",
+			input:       []byte(`<html><body><h1>Title</h1><p>Some <a href="/x">linked</a> text</p></body></html>`),
+			expect:      Content{Markdown: "# Title\n\nSome linked text"},
+		},
+		{
+			name:        "latin1 html",
+			contentType: "text/html; charset=iso-8859-1",
+			input:       []byte("<p>Gr\xfc\xdfe</p>"),
+			expect:      Content{Markdown: "Grüße"},
+		},
+		{
+			name:        "detect html",
+			contentType: "",
+			input:       []byte(`<!DOCTYPE html><html><body><p>detected</p></body></html>`),
+			expect:      Content{Markdown: "detected"},
+		},
+		{
+			name:        "markdown",
+			contentType: "text/markdown",
+			input:       []byte("# Title\n\nSee [docs](https://example.org)"),
+			expect:      Content{Markdown: "# Title\n\nSee docs"},
+		},
+		{
+			name:        "pdf",
+			contentType: "application/pdf",
+			input:       minimalPDF("Manual", "First page", "Second page"),
+			expect:      Content{Title: "Manual", Pages: []string{"First page", "Second page"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := (&ContentParser{}).Parse(tc.contentType, tc.input)
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, *actual)
+		})
+	}
+}
+
+func TestParseContentUnsupported(t *testing.T) {
+	_, err := (&ContentParser{}).Parse("image/png", []byte{0x89, 'P', 'N', 'G'})
+
+	require.ErrorIs(t, err, ErrUnsupportedContentType)
+}
+
+// minimalPDF generates a PDF document that contains one page per given text.
+func minimalPDF(title string, pages ...string) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // page tree, set below
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< /Title (%s) >>", title),
+	}
+	kids := ""
+
+	for _, text := range pages {
+		stream := fmt.Sprintf("BT /F1 12 Tf 72 712 Td (%s) Tj ET", text)
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream))
+		contentRef := len(objects)
+		objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", contentRef))
+		kids += fmt.Sprintf("%d 0 R ", len(objects))
+	}
+
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, len(pages))
+
+	var buf bytes.Buffer
+
+	buf.WriteString("%PDF-1.4\n")
+
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xrefOffset := buf.Len()
+
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 4 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xrefOffset)
+
+	return buf.Bytes()
+}
@@ -2,37 +2,72 @@ package crawler
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"net/url"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
 	"github.com/gocolly/colly"
+	"github.com/mgoltzsche/knowledgebot/internal/importer"
+	"github.com/temoto/robotstxt"
 	"github.com/tmc/langchaingo/schema"
-	"github.com/tmc/langchaingo/textsplitter"
 	"github.com/tmc/langchaingo/vectorstores"
 )
 
 type Crawler struct {
+	importer.Chunker
+	importer.ContentParser
 	MaxDepth         int
 	MaxPages         uint64
 	URLRegex         *regexp.Regexp
-	ChunkSize        int
-	ChunkOverlap     int
-	Sink             vectorstores.VectorStore
-	mutex            sync.Mutex
-	knownChunkHashes map[string]struct{}
+	Sitemap          bool
+	SitemapURLs      []string
+	RespectRobotsTxt bool
+	StateFile        string
+	// Resume continues the unfinished crawl recorded within the StateFile, if any, instead of starting from the seed URL.
+	Resume bool
+	// Parallelism is the maximum number of concurrent requests.
+	Parallelism int
+	// Delay is the duration to wait for between requests to the same domain.
+	Delay time.Duration
+	// RandomDelay is the maximum random duration that is added to Delay.
+	RandomDelay time.Duration
+	// RequestTimeout is the maximum duration of a request.
+	RequestTimeout time.Duration
+	// MaxRetries is the number of times a request is retried after a 429 or 5xx response or a connection error.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry which doubles with every further attempt.
+	// A longer delay specified by the Retry-After response header takes precedence.
+	RetryBackoff time.Duration
+	Sink         vectorstores.VectorStore
+}
+
+// indexOp describes a change of the indexed chunks of a page.
+type indexOp struct {
+	url     string
+	chunks  []schema.Document
+	replace bool       // delete the previously indexed chunks of the page first
+	page    *PageState // state to record for the page, nil removes the page from the state
+}
+
+type crawledPage struct {
+	url     string
+	state   PageState
+	chunks  []schema.Document
+	replace bool
 }
 
 func (s *Crawler) Crawl(ctx context.Context, seedURL string) error {
-	slog.Info("crawling "+seedURL, "maxDepth", s.MaxDepth, "maxPages", s.MaxPages, "urlRegex", s.URLRegex)
+	slog.Info("crawling "+seedURL, "maxDepth", s.MaxDepth, "maxPages", s.MaxPages, "urlRegex", s.URLRegex,
+		"sitemap", s.Sitemap || len(s.SitemapURLs) > 0, "respectRobotsTxt", s.RespectRobotsTxt, "stateFile", s.StateFile, "resume", s.Resume,
+		"parallelism", s.Parallelism, "delay", s.Delay, "randomDelay", s.RandomDelay, "maxRetries", s.MaxRetries)
 
 	startTime := time.Now()
 
@@ -41,26 +76,132 @@ func (s *Crawler) Crawl(ctx context.Context, seedURL string) error {
 		return err
 	}
 
+	if s.Resume && s.StateFile == "" {
+		return errors.New("resume crawl: no state file specified")
+	}
+
+	var (
+		state  *State
+		resume bool
+	)
+
+	if s.StateFile != "" {
+		if _, ok := s.Sink.(importer.DocumentDeleter); !ok {
+			return errors.New("incremental crawl: the vector store does not support deleting documents")
+		}
+
+		state, err = LoadState(s.StateFile)
+		if err != nil {
+			return err
+		}
+
+		resume = s.Resume && state.Resumable(u.String())
+
+		if resume {
+			slog.Info(fmt.Sprintf("resuming crawl with %d pending and %d visited page(s)", len(state.Frontier()), len(state.VisitedURLs())))
+
+			s.AddKnownChunkHashes(state.ChunkHashes())
+		} else {
+			if s.Resume {
+				slog.Info("no unfinished crawl to resume, starting from the seed URL")
+			}
+
+			state.ResetProgress(u.String())
+		}
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	ch := make(chan []schema.Document, 50)
+	ch := make(chan indexOp, 50)
 
-	go s.crawl(ctx, u, ch)
+	go s.crawl(ctx, u, state, resume, ch)
+
+	stopCheckpoints := s.saveStatePeriodically(state)
+
+	err = s.indexDocumentChunks(ctx, cancel, state, ch, startTime)
+
+	stopCheckpoints()
+
+	if state != nil {
+		if err == nil {
+			state.CompleteProgress()
+		} else {
+			slog.Info("saving the crawl progress, continue the crawl using --resume")
+		}
 
-	return s.indexDocumentChunks(ctx, cancel, ch, startTime)
+		if e := state.Save(s.StateFile); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return err
 }
 
-func (s *Crawler) crawl(ctx context.Context, seedURL *url.URL, ch chan<- []schema.Document) {
+// saveStatePeriodically saves the given state regularly so that the crawl can be resumed after the process got killed.
+// It returns a function that stops saving the state.
+func (s *Crawler) saveStatePeriodically(state *State) func() {
+	if state == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(checkpointInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := state.Save(s.StateFile)
+				if err != nil {
+					slog.Warn(err.Error())
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func (s *Crawler) crawl(ctx context.Context, seedURL *url.URL, state *State, resume bool, ch chan<- indexOp) {
 	defer close(ch)
 
+	var (
+		mutex   sync.Mutex
+		pending = map[uint32]*crawledPage{}
+		visited = map[string]struct{}{}
+		retries = map[string]int{}
+		depths  = map[string]int{}
+	)
+
 	pageCounter := atomic.Uint64{}
+	limitReached := atomic.Bool{}
+	retrying := newPendingRetries()
+	timeout := s.RequestTimeout
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+
+	httpClient := &http.Client{Timeout: timeout}
 	domain := strings.TrimPrefix(seedURL.Host, "www.")
+	domains := []string{seedURL.Hostname(), domain}
+	// Colly's charset detection is not enabled since it corrupts binary documents such as PDFs.
+	// Instead, HTML documents are decoded by importer.ContentParser.
+	// The crawl depth is tracked by the crawler itself since colly cannot resume a request at a given depth.
 	opts := []func(*colly.Collector){
-		colly.MaxDepth(s.MaxDepth),
-		colly.AllowedDomains(seedURL.Hostname(), domain),
-		colly.DetectCharset(),
-		colly.UserAgent("knowledgebot"),
+		colly.AllowedDomains(domains...),
+		colly.UserAgent(userAgent),
+		colly.Async(true),
 	}
 
 	if s.URLRegex != nil {
@@ -68,6 +209,90 @@ func (s *Crawler) crawl(ctx context.Context, seedURL *url.URL, ch chan<- []schem
 	}
 
 	c := colly.NewCollector(opts...)
+	c.IgnoreRobotsTxt = !s.RespectRobotsTxt
+	c.SetRequestTimeout(timeout)
+
+	var robots *robotstxt.RobotsData
+
+	if s.RespectRobotsTxt || s.Sitemap {
+		r, err := fetchRobotsTxt(ctx, httpClient, seedURL)
+		if err != nil {
+			slog.Warn(err.Error())
+		}
+
+		robots = r
+	}
+
+	delay := s.Delay
+
+	if s.RespectRobotsTxt && robots != nil {
+		if crawlDelay := robots.FindGroup(userAgent).CrawlDelay; crawlDelay > delay {
+			slog.Info(fmt.Sprintf("applying robots.txt crawl delay of %s", crawlDelay))
+
+			delay = crawlDelay
+		}
+	}
+
+	err := c.Limit(&colly.LimitRule{
+		DomainGlob:  "*",
+		Parallelism: max(s.Parallelism, 1),
+		Delay:       delay,
+		RandomDelay: s.RandomDelay,
+	})
+	if err != nil {
+		slog.Warn("failed to apply crawl rate limit: " + err.Error())
+	}
+
+	markVisited := func(u string) {
+		mutex.Lock()
+		visited[u] = struct{}{}
+		mutex.Unlock()
+	}
+
+	// markDone marks a page that did not result in an index operation as visited within the persisted state.
+	// Pages that resulted in an index operation are marked as visited once their chunks have been indexed.
+	markDone := func(u string) {
+		if state != nil {
+			state.MarkVisited(u)
+		}
+	}
+
+	depthOf := func(u string) int {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if depth, ok := depths[u]; ok {
+			return depth
+		}
+
+		return 1
+	}
+
+	visit := func(u string, depth int) {
+		if s.MaxDepth > 0 && depth > s.MaxDepth {
+			return
+		}
+
+		mutex.Lock()
+		if _, ok := depths[u]; !ok {
+			depths[u] = depth
+		}
+		mutex.Unlock()
+
+		// Add the URL to the frontier before visiting it since the asynchronous request may complete before c.Visit returns.
+		if state != nil && !state.AddToFrontier(u, depth) {
+			return
+		}
+
+		err := c.Visit(u)
+		if err != nil {
+			if state != nil {
+				state.RemoveFromFrontier(u)
+			}
+
+			slog.Debug("failed to visit page: " + err.Error())
+		}
+	}
 
 	c.OnRequest(func(req *colly.Request) {
 		select {
@@ -77,141 +302,407 @@ func (s *Crawler) crawl(ctx context.Context, seedURL *url.URL, ch chan<- []schem
 		default:
 		}
 
-		if s.MaxPages > 0 {
+		mutex.Lock()
+		retry := retries[req.URL.String()] > 0
+		mutex.Unlock()
+
+		if s.MaxPages > 0 && !retry {
 			if pageCounter.Add(1) > s.MaxPages {
+				limitReached.Store(true)
 				req.Abort()
 				return
 			}
 		}
 
+		if state != nil {
+			if p := state.Page(req.URL.String()); p != nil {
+				if p.ETag != "" {
+					req.Headers.Set("If-None-Match", p.ETag)
+				}
+
+				if p.LastModified != "" {
+					req.Headers.Set("If-Modified-Since", p.LastModified)
+				}
+			}
+		}
+
 		slog.Info("visiting " + req.URL.String())
 	})
 
 	c.OnResponse(func(f *colly.Response) {
-		err := s.processHTML(ctx, f.Request.URL, string(f.Body), ch)
+		page, err := s.processResponse(f.Request.URL, f.Headers.Get("Content-Type"), f.Body, state)
 		if err != nil {
-			slog.Warn(err.Error())
+			if errors.Is(err, importer.ErrUnsupportedContentType) {
+				slog.Debug(err.Error())
+			} else {
+				// Keep the chunks of a reachable page that could not be processed this time.
+				markVisited(f.Request.URL.String())
+				slog.Warn(err.Error())
+			}
+
+			return
 		}
+
+		page.state.ETag = f.Headers.Get("ETag")
+		page.state.LastModified = f.Headers.Get("Last-Modified")
+
+		mutex.Lock()
+		pending[f.Request.ID] = page
+		mutex.Unlock()
 	})
 
 	c.OnHTML("a[href]", func(e *colly.HTMLElement) {
-		err := e.Request.Visit(e.Attr("href"))
-		if err != nil {
-			slog.Debug("failed to visit page: " + err.Error())
+		link := e.Request.AbsoluteURL(e.Attr("href"))
+		if link == "" {
+			return
 		}
+
+		if state != nil && s.inScope(link, domains) {
+			mutex.Lock()
+			if page := pending[e.Request.ID]; page != nil && !slices.Contains(page.state.Links, link) {
+				page.state.Links = append(page.state.Links, link)
+			}
+			mutex.Unlock()
+		}
+
+		visit(link, depthOf(e.Request.URL.String())+1)
 	})
 
-	err := c.Visit(seedURL.String())
-	if err != nil {
-		slog.Warn("failed to crawl page: " + err.Error())
-	}
+	c.OnScraped(func(f *colly.Response) {
+		mutex.Lock()
+		page := pending[f.Request.ID]
+		delete(pending, f.Request.ID)
+		mutex.Unlock()
 
-	c.Wait()
-}
+		if page == nil {
+			markDone(f.Request.URL.String())
+			return
+		}
 
-func (s *Crawler) processHTML(ctx context.Context, url *url.URL, html string, ch chan<- []schema.Document) error {
-	markdown, err := htmltomarkdown.ConvertString(html)
-	if err != nil {
-		return fmt.Errorf("html to markdown: %w", err)
-	}
+		markVisited(page.url)
 
-	markdown = stripMarkdownLinks(markdown)
+		if len(page.chunks) > 0 || page.replace || state != nil {
+			ch <- indexOp{
+				url:     page.url,
+				chunks:  page.chunks,
+				replace: page.replace,
+				page:    &page.state,
+			}
+		}
+	})
 
-	splitter := textsplitter.NewMarkdownTextSplitter(
-		textsplitter.WithChunkSize(s.ChunkSize),
-		textsplitter.WithChunkOverlap(s.ChunkOverlap),
-	)
+	c.OnError(func(f *colly.Response, err error) {
+		u := f.Request.URL.String()
 
-	chunks, err := splitter.SplitText(markdown)
-	if err != nil {
-		return fmt.Errorf("split text: %w", err)
+		switch f.StatusCode {
+		case http.StatusNotModified:
+			markVisited(u)
+
+			slog.Info("unchanged " + u)
+
+			if state == nil {
+				return
+			}
+
+			if p := state.Page(u); p != nil {
+				for _, link := range p.Links {
+					visit(link, depthOf(u)+1)
+				}
+			}
+
+			markDone(u)
+		case http.StatusNotFound, http.StatusGone:
+			// Marked as visited in order to not delete the chunks again as an unreachable page.
+			markVisited(u)
+
+			if state != nil && state.Page(u) != nil {
+				slog.Info("deleting chunks of removed page " + u)
+
+				ch <- indexOp{url: u, replace: true}
+			} else {
+				markDone(u)
+			}
+		default:
+			fail := func() {
+				markVisited(u)
+				markDone(u)
+
+				slog.Warn(fmt.Sprintf("failed to crawl %s: %s", u, err))
+			}
+
+			if isRetryable(f.StatusCode) && s.scheduleRetry(ctx, f, retries, &mutex, retrying, fail) {
+				return
+			}
+
+			fail()
+		}
+	})
+
+	if resume {
+		mutex.Lock()
+		for _, u := range state.VisitedURLs() {
+			visited[u] = struct{}{}
+		}
+		mutex.Unlock()
+
+		// The frontier's URLs are visited directly since they are part of the frontier already.
+		for u, depth := range state.Frontier() {
+			mutex.Lock()
+			depths[u] = depth
+			mutex.Unlock()
+
+			err := c.Visit(u)
+			if err != nil {
+				state.RemoveFromFrontier(u)
+				slog.Debug("failed to visit page: " + err.Error())
+			}
+		}
+	} else {
+		visit(seedURL.String(), 1)
+
+		sitemapURLs := s.sitemapURLs(seedURL, robots)
+		if len(sitemapURLs) > 0 {
+			seedURLs := sitemapSeedURLs(ctx, httpClient, sitemapURLs)
+
+			slog.Info(fmt.Sprintf("seeding crawl with %d URLs from %d sitemap(s)", len(seedURLs), len(sitemapURLs)))
+
+			for _, u := range seedURLs {
+				if ctx.Err() != nil {
+					break
+				}
+
+				visit(u, 1)
+			}
+		}
 	}
 
-	slog.Info(fmt.Sprintf("scraped %d chunks from %s", len(chunks), url))
+	// Retries are started after a delay, outside of the collector, and may fail again, scheduling further retries.
+	for {
+		started := retrying.started()
 
-	docs := make([]schema.Document, 0, len(chunks))
+		c.Wait()
 
-	for _, chunk := range chunks {
-		if s.knownChunk(chunk) {
-			continue
+		if retrying.wait() == started {
+			break
 		}
+	}
+
+	if state != nil && ctx.Err() == nil && !limitReached.Load() {
+		// Delete the chunks of previously indexed pages that are no longer reachable.
+		for _, u := range state.PageURLs() {
+			if _, ok := visited[u]; ok || !s.inScope(u, domains) {
+				continue
+			}
 
-		docs = append(docs, schema.Document{
-			PageContent: chunk,
-			Metadata: map[string]any{
-				"url":   url.String(),
-				"title": deriveTitle(markdown, url),
-			},
-		})
+			slog.Info("deleting chunks of unreachable page " + u)
+
+			ch <- indexOp{url: u, replace: true}
+		}
 	}
+}
 
-	if len(docs) > 0 {
-		ch <- docs
+// pendingRetries tracks the retries that are waiting for their delay to elapse.
+type pendingRetries struct {
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	pending int
+	// startedCount is the number of retries that were started or abandoned.
+	startedCount int
+}
+
+func newPendingRetries() *pendingRetries {
+	r := &pendingRetries{}
+	r.cond = sync.NewCond(&r.mutex)
+
+	return r
+}
+
+func (r *pendingRetries) add() {
+	r.mutex.Lock()
+	r.pending++
+	r.mutex.Unlock()
+}
+
+// done is called after the retry was started or abandoned.
+func (r *pendingRetries) done() {
+	r.mutex.Lock()
+	r.pending--
+	r.startedCount++
+	r.cond.Broadcast()
+	r.mutex.Unlock()
+}
+
+func (r *pendingRetries) started() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.startedCount
+}
+
+// wait waits until there are no pending retries and returns the number of started retries.
+func (r *pendingRetries) wait() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for r.pending > 0 {
+		r.cond.Wait()
 	}
 
-	return nil
+	return r.startedCount
 }
 
-func (s *Crawler) knownChunk(chunk string) bool {
-	h := sha256.New()
-	_, _ = h.Write([]byte(chunk))
-	b := h.Sum(nil)
-	key := hex.EncodeToString(b)
+// scheduleRetry retries the request of the given failed response after a backoff delay.
+// The delay is awaited asynchronously in order to not occupy one of the collector's parallel request slots.
+// It returns false if the request must not be retried (anymore), otherwise fail is called when the retry cannot be started.
+func (s *Crawler) scheduleRetry(ctx context.Context, f *colly.Response, retries map[string]int, mutex *sync.Mutex, pending *pendingRetries, fail func()) bool {
+	u := f.Request.URL.String()
 
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	mutex.Lock()
+	attempt := retries[u] + 1
+	if attempt <= s.MaxRetries {
+		retries[u] = attempt
+	}
+	mutex.Unlock()
+
+	if attempt > s.MaxRetries || ctx.Err() != nil {
+		return false
+	}
 
-	if s.knownChunkHashes == nil {
-		s.knownChunkHashes = map[string]struct{}{}
+	delay := retryDelay(attempt, s.RetryBackoff, f.Headers, time.Now())
+	if delay > maxRetryDelay {
+		slog.Warn(fmt.Sprintf("not retrying %s since the server requested a delay of %s", u, delay))
+		return false
 	}
 
-	_, known := s.knownChunkHashes[key]
-	if !known {
-		s.knownChunkHashes[key] = struct{}{}
+	slog.Info(fmt.Sprintf("retrying %s in %s (attempt %d/%d)", u, delay, attempt, s.MaxRetries), "status", f.StatusCode)
+
+	pending.add()
+
+	go func() {
+		// Marked as done after the retry was started, so that waiting for the collector covers it.
+		defer pending.done()
+
+		select {
+		case <-ctx.Done():
+			fail()
+			return
+		case <-time.After(delay):
+		}
+
+		err := f.Request.Retry()
+		if err != nil {
+			slog.Warn(fmt.Sprintf("failed to retry %s: %s", u, err))
+			fail()
+		}
+	}()
+
+	return true
+}
+
+// inScope returns true if the given URL is within the crawl's domains and matches the URL filter.
+func (s *Crawler) inScope(u string, domains []string) bool {
+	pu, err := url.Parse(u)
+	if err != nil || !slices.Contains(domains, pu.Host) {
+		return false
 	}
 
-	return known
+	return s.URLRegex == nil || s.URLRegex.MatchString(u)
 }
 
-func deriveTitle(markdown string, u *url.URL) string {
-	for _, line := range strings.Split(markdown, "\n") {
-		if strings.HasPrefix(line, "# ") {
-			title := strings.Trim(line[2:], "*_ ")
-			if len(title) > 0 {
-				return fmt.Sprintf("%s | %s", title, u.Hostname())
-			}
+func (s *Crawler) sitemapURLs(seedURL *url.URL, robots *robotstxt.RobotsData) []string {
+	urls := slices.Clone(s.SitemapURLs)
+
+	if s.Sitemap {
+		if robots != nil && len(robots.Sitemaps) > 0 {
+			urls = append(urls, robots.Sitemaps...)
+		} else {
+			urls = append(urls, seedURL.ResolveReference(&url.URL{Path: "/sitemap.xml"}).String())
 		}
 	}
 
-	pathSegments := strings.Split(u.Path, "/")
-	lastPathSegment := pathSegments[len(pathSegments)-1]
+	return urls
+}
 
-	if lastPathSegment == "" || lastPathSegment == "." && len(pathSegments) > 1 {
-		lastPathSegment = pathSegments[len(pathSegments)-2]
-		if lastPathSegment == "" || lastPathSegment == "." {
-			lastPathSegment = u.Path
+func (s *Crawler) processResponse(url *url.URL, contentType string, body []byte, state *State) (*crawledPage, error) {
+	content, err := s.Parse(contentType, body)
+	if err != nil {
+		return nil, fmt.Errorf("process %s: %w", url, err)
+	}
+
+	page := &crawledPage{
+		url: url.String(),
+		state: PageState{
+			ContentHash: importer.HashString(content.Text()),
+		},
+	}
+
+	if state != nil {
+		if prev := state.Page(page.url); prev != nil {
+			if prev.ContentHash == page.state.ContentHash {
+				slog.Info("unchanged " + page.url)
+				return page, nil
+			}
+
+			page.replace = true
 		}
 	}
 
-	return fmt.Sprintf("%s | %s", lastPathSegment, u.Hostname())
+	page.chunks, err = s.ChunkContent(content, map[string]any{
+		"url":   url.String(),
+		"title": content.DeriveTitle(url),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("process %s: %w", url, err)
+	}
+
+	slog.Info(fmt.Sprintf("scraped %d chunks from %s", len(page.chunks), url))
+
+	return page, nil
 }
 
-func (s *Crawler) indexDocumentChunks(ctx context.Context, cancel context.CancelFunc, ch <-chan []schema.Document, startTime time.Time) error {
+func (s *Crawler) indexDocumentChunks(ctx context.Context, cancel context.CancelFunc, state *State, ch <-chan indexOp, startTime time.Time) error {
 	var err error
 
 	docCount := 0
 	chunkCount := 0
+	deletedCount := 0
+	deleter, _ := s.Sink.(importer.DocumentDeleter)
 
-	for chunks := range ch {
+	for op := range ch {
 		if err == nil {
-			_, e := s.Sink.AddDocuments(ctx, chunks)
+			var e error
+
+			switch {
+			case op.replace && len(op.chunks) == 0:
+				// The page was removed or is unreachable.
+				e = deleter.DeleteDocumentsByURL(ctx, op.url)
+			case op.replace:
+				e = importer.ReplaceDocuments(ctx, s.Sink, op.url, op.chunks)
+			case len(op.chunks) > 0:
+				_, e = s.Sink.AddDocuments(ctx, op.chunks)
+			}
+
 			if e != nil {
 				err = e
 				cancel()
+				continue
+			}
+
+			if len(op.chunks) > 0 {
+				docCount++
+				chunkCount += len(op.chunks)
+			} else if op.replace {
+				deletedCount++
 			}
 
-			docCount++
-			chunkCount += len(chunks)
+			if state != nil {
+				state.SetPage(op.url, op.page)
+				state.MarkVisited(op.url)
+
+				for _, chunk := range op.chunks {
+					state.AddChunkHashes(importer.HashString(chunk.PageContent))
+				}
+			}
 		}
 	}
 
@@ -221,7 +712,7 @@ func (s *Crawler) indexDocumentChunks(ctx context.Context, cancel context.Cancel
 
 	elapsed := time.Since(startTime)
 
-	slog.Info(fmt.Sprintf("indexed %d chunks of %d document(s) in %s", chunkCount, docCount, elapsed))
+	slog.Info(fmt.Sprintf("indexed %d chunks of %d document(s) and deleted %d document(s) in %s", chunkCount, docCount, deletedCount, elapsed))
 
 	return ctx.Err()
 }
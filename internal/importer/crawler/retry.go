@@ -0,0 +1,54 @@
+package crawler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultRequestTimeout is the request timeout used when none is specified.
+	defaultRequestTimeout = 30 * time.Second
+	// maxRetryDelay is the longest delay the crawler waits for before retrying a request.
+	maxRetryDelay = 5 * time.Minute
+)
+
+// isRetryable returns true if a request that failed with the given status code may succeed when retried.
+// A status code of 0 indicates a connection error or timeout.
+func isRetryable(statusCode int) bool {
+	return statusCode == 0 || statusCode == http.StatusTooManyRequests || statusCode >= 500 && statusCode != http.StatusNotImplemented
+}
+
+// retryDelay returns the delay before the given retry attempt (starting at 1).
+// The delay doubles with every attempt and is extended to the duration specified by the Retry-After header, if any.
+func retryDelay(attempt int, backoff time.Duration, header *http.Header, now time.Time) time.Duration {
+	delay := backoff << min(attempt-1, 16)
+
+	if header != nil {
+		if retryAfter, ok := parseRetryAfter(header.Get("Retry-After"), now); ok && retryAfter > delay {
+			delay = retryAfter
+		}
+	}
+
+	return delay
+}
+
+// parseRetryAfter parses the value of a Retry-After header which specifies either seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	return max(t.Sub(now), 0), true
+}
@@ -0,0 +1,37 @@
+package crawler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryDelay(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name       string
+		attempt    int
+		retryAfter string
+		expect     time.Duration
+	}{
+		{"first attempt", 1, "", time.Second},
+		{"third attempt", 3, "", 4 * time.Second},
+		{"retry-after seconds", 1, "30", 30 * time.Second},
+		{"retry-after date", 1, "Sun, 18 Oct 2026 12:01:00 GMT", time.Minute},
+		{"retry-after in the past", 2, "Sun, 18 Oct 2026 11:00:00 GMT", 2 * time.Second},
+		{"shorter retry-after", 3, "1", 4 * time.Second},
+		{"invalid retry-after", 1, "soon", time.Second},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{}
+			if tc.retryAfter != "" {
+				header.Set("Retry-After", tc.retryAfter)
+			}
+
+			require.Equal(t, tc.expect, retryDelay(tc.attempt, time.Second, &header, now))
+		})
+	}
+}
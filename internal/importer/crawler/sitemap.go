@@ -0,0 +1,162 @@
+package crawler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/temoto/robotstxt"
+)
+
+const (
+	userAgent       = "knowledgebot"
+	maxSitemapDepth = 5
+	maxSitemapSize  = 50 << 20
+)
+
+type sitemapXML struct {
+	URLs     []sitemapLocation `xml:"url"`
+	Sitemaps []sitemapLocation `xml:"sitemap"`
+}
+
+type sitemapLocation struct {
+	Loc string `xml:"loc"`
+}
+
+// fetchRobotsTxt fetches and parses the robots.txt of the given URL's host.
+func fetchRobotsTxt(ctx context.Context, client *http.Client, u *url.URL) (*robotstxt.RobotsData, error) {
+	robotsURL := fmt.Sprintf("%s://%s/robots.txt", u.Scheme, u.Host)
+
+	resp, err := httpGet(ctx, client, robotsURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetch robots.txt: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	robots, err := robotstxt.FromResponse(resp)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", robotsURL, err)
+	}
+
+	return robots, nil
+}
+
+// sitemapSeedURLs returns the page URLs listed within the given sitemaps.
+// Sitemap index files are resolved recursively.
+func sitemapSeedURLs(ctx context.Context, client *http.Client, sitemapURLs []string) []string {
+	seen := map[string]struct{}{}
+	urls := make([]string, 0, 100)
+
+	var fetch func(sitemapURL string, depth int)
+
+	fetch = func(sitemapURL string, depth int) {
+		if _, ok := seen[sitemapURL]; ok || depth > maxSitemapDepth || ctx.Err() != nil {
+			return
+		}
+
+		seen[sitemapURL] = struct{}{}
+
+		pages, sitemaps, err := fetchSitemap(ctx, client, sitemapURL)
+		if err != nil {
+			slog.Warn(err.Error())
+			return
+		}
+
+		slog.Debug(fmt.Sprintf("found %d pages and %d sitemaps within %s", len(pages), len(sitemaps), sitemapURL))
+
+		urls = append(urls, pages...)
+
+		for _, u := range sitemaps {
+			fetch(u, depth+1)
+		}
+	}
+
+	for _, u := range sitemapURLs {
+		fetch(u, 1)
+	}
+
+	return urls
+}
+
+func fetchSitemap(ctx context.Context, client *http.Client, sitemapURL string) (pages, sitemaps []string, err error) {
+	resp, err := httpGet(ctx, client, sitemapURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch sitemap: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("fetch sitemap %s: server responded with %s", sitemapURL, resp.Status)
+	}
+
+	pages, sitemaps, err = parseSitemap(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse sitemap %s: %w", sitemapURL, err)
+	}
+
+	return pages, sitemaps, nil
+}
+
+// parseSitemap parses a (gzipped) sitemap or sitemap index file.
+// The uncompressed size is limited to maxSitemapSize.
+// See https://www.sitemaps.org/protocol.html
+func parseSitemap(r io.Reader) (pages, sitemaps []string, err error) {
+	br := bufio.NewReader(r)
+
+	magic, _ := br.Peek(2)
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		defer gz.Close()
+
+		r = gz
+	} else {
+		r = br
+	}
+
+	var sitemap sitemapXML
+
+	err = xml.NewDecoder(io.LimitReader(r, maxSitemapSize)).Decode(&sitemap)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pages = make([]string, 0, len(sitemap.URLs))
+	for _, l := range sitemap.URLs {
+		if loc := strings.TrimSpace(l.Loc); loc != "" {
+			pages = append(pages, loc)
+		}
+	}
+
+	sitemaps = make([]string, 0, len(sitemap.Sitemaps))
+	for _, l := range sitemap.Sitemaps {
+		if loc := strings.TrimSpace(l.Loc); loc != "" {
+			sitemaps = append(sitemaps, loc)
+		}
+	}
+
+	return pages, sitemaps, nil
+}
+
+func httpGet(ctx context.Context, client *http.Client, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", userAgent)
+
+	return client.Do(req)
+}
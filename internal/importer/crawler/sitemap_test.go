@@ -0,0 +1,92 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSitemap(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		input          string
+		gzip           bool
+		expectPages    []string
+		expectSitemaps []string
+	}{
+		{
+			name: "url set",
+			input: `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.org/a</loc><lastmod>2025-01-01</lastmod></url>
+  <url><loc>
+    https://example.org/b
+  </loc></url>
+</urlset>`,
+			expectPages:    []string{"https://example.org/a", "https://example.org/b"},
+			expectSitemaps: []string{},
+		},
+		{
+			name: "sitemap index",
+			input: `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://example.org/sitemap1.xml.gz</loc></sitemap>
+  <sitemap><loc>https://example.org/sitemap2.xml</loc></sitemap>
+</sitemapindex>`,
+			expectPages:    []string{},
+			expectSitemaps: []string{"https://example.org/sitemap1.xml.gz", "https://example.org/sitemap2.xml"},
+		},
+		{
+			name:           "gzipped url set",
+			input:          `<urlset><url><loc>https://example.org/a</loc></url></urlset>`,
+			gzip:           true,
+			expectPages:    []string{"https://example.org/a"},
+			expectSitemaps: []string{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			input := []byte(tc.input)
+
+			if tc.gzip {
+				var buf bytes.Buffer
+				w := gzip.NewWriter(&buf)
+				_, err := w.Write(input)
+				require.NoError(t, err)
+				require.NoError(t, w.Close())
+				input = buf.Bytes()
+			}
+
+			pages, sitemaps, err := parseSitemap(bytes.NewReader(input))
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expectPages, pages, "pages")
+			require.Equal(t, tc.expectSitemaps, sitemaps, "sitemaps")
+		})
+	}
+}
+
+func TestParseSitemapInvalid(t *testing.T) {
+	_, _, err := parseSitemap(strings.NewReader("not xml"))
+
+	require.Error(t, err)
+}
+
+func TestParseSitemapLimitsUncompressedSize(t *testing.T) {
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(`<urlset><url><loc>https://example.org/a</loc></url>`))
+	require.NoError(t, err)
+	_, err = gz.Write(bytes.Repeat([]byte(" "), maxSitemapSize))
+	require.NoError(t, err)
+	_, err = gz.Write([]byte(`</urlset>`))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	_, _, err = parseSitemap(&buf)
+
+	require.Error(t, err)
+}
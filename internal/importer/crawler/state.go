@@ -0,0 +1,239 @@
+package crawler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// checkpointInterval is the interval in which the state of a running crawl is saved.
+const checkpointInterval = 30 * time.Second
+
+// State is the crawl state that is persisted between crawler runs in order to re-crawl a site incrementally.
+type State struct {
+	Pages map[string]*PageState `json:"pages"`
+	// Progress holds the progress of an unfinished crawl or nil when the last crawl completed.
+	Progress *Progress `json:"progress,omitempty"`
+	mutex    sync.Mutex
+}
+
+// Progress is the state of an unfinished crawl that is required to resume it.
+type Progress struct {
+	SeedURL string `json:"seedURL"`
+	// Frontier maps the URLs of the pages that are yet to be crawled to their crawl depth.
+	Frontier map[string]int `json:"frontier"`
+	// Visited holds the URLs of the pages that have been crawled and indexed.
+	Visited map[string]bool `json:"visited"`
+	// ChunkHashes holds the hashes of the chunks that have been indexed.
+	ChunkHashes map[string]bool `json:"chunkHashes"`
+}
+
+// PageState holds the information required to detect whether a previously indexed page has changed.
+type PageState struct {
+	ContentHash  string   `json:"contentHash"`
+	ETag         string   `json:"etag,omitempty"`
+	LastModified string   `json:"lastModified,omitempty"`
+	Links        []string `json:"links,omitempty"`
+}
+
+// LoadState loads the crawl state from the given file.
+// It returns an empty state when the file does not exist.
+func LoadState(file string) (*State, error) {
+	state := &State{Pages: map[string]*PageState{}}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+
+		return nil, fmt.Errorf("load crawl state: %w", err)
+	}
+
+	err = json.Unmarshal(b, state)
+	if err != nil {
+		return nil, fmt.Errorf("load crawl state: %s: %w", file, err)
+	}
+
+	if state.Pages == nil {
+		state.Pages = map[string]*PageState{}
+	}
+
+	return state, nil
+}
+
+// Save writes the crawl state atomically into the given file.
+func (s *State) Save(file string) error {
+	s.mutex.Lock()
+	b, err := json.Marshal(s)
+	s.mutex.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("save crawl state: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(file), ".tmp-"+filepath.Base(file)+"-")
+	if err != nil {
+		return fmt.Errorf("save crawl state: %w", err)
+	}
+
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.Write(b)
+	if e := tmpFile.Close(); e != nil && err == nil {
+		err = e
+	}
+
+	if err != nil {
+		return fmt.Errorf("save crawl state: %w", err)
+	}
+
+	err = os.Rename(tmpFile.Name(), file)
+	if err != nil {
+		return fmt.Errorf("save crawl state: %w", err)
+	}
+
+	return nil
+}
+
+// Page returns a copy of the state of the given page or nil if the page is unknown.
+func (s *State) Page(url string) *PageState {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p, ok := s.Pages[url]
+	if !ok {
+		return nil
+	}
+
+	c := *p
+
+	return &c
+}
+
+// SetPage sets the state of the given page or removes it when nil is provided.
+func (s *State) SetPage(url string, page *PageState) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if page == nil {
+		delete(s.Pages, url)
+		return
+	}
+
+	s.Pages[url] = page
+}
+
+// PageURLs returns the URLs of all known pages.
+func (s *State) PageURLs() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	urls := make([]string, 0, len(s.Pages))
+	for u := range s.Pages {
+		urls = append(urls, u)
+	}
+
+	return urls
+}
+
+// ResetProgress discards the progress of a previous unfinished crawl and starts recording the progress of a new crawl.
+func (s *State) ResetProgress(seedURL string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Progress = &Progress{
+		SeedURL:     seedURL,
+		Frontier:    map[string]int{},
+		Visited:     map[string]bool{},
+		ChunkHashes: map[string]bool{},
+	}
+}
+
+// CompleteProgress discards the recorded progress after the crawl completed.
+func (s *State) CompleteProgress() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Progress = nil
+}
+
+// Resumable returns true if the state holds the progress of an unfinished crawl starting at the given seed URL.
+func (s *State) Resumable(seedURL string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.Progress != nil && s.Progress.SeedURL == seedURL && len(s.Progress.Frontier) > 0
+}
+
+// Frontier returns a copy of the crawl frontier.
+func (s *State) Frontier() map[string]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return maps.Clone(s.Progress.Frontier)
+}
+
+// VisitedURLs returns the URLs of the pages that have been crawled and indexed.
+func (s *State) VisitedURLs() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return slices.Collect(maps.Keys(s.Progress.Visited))
+}
+
+// ChunkHashes returns the hashes of the chunks that have been indexed.
+func (s *State) ChunkHashes() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return slices.Collect(maps.Keys(s.Progress.ChunkHashes))
+}
+
+// AddToFrontier adds the given URL to the crawl frontier.
+// It returns false if the URL has been added or visited before.
+func (s *State) AddToFrontier(url string, depth int) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.Progress.Frontier[url]; ok || s.Progress.Visited[url] {
+		return false
+	}
+
+	s.Progress.Frontier[url] = depth
+
+	return true
+}
+
+// RemoveFromFrontier removes the given URL from the crawl frontier without marking it as visited.
+func (s *State) RemoveFromFrontier(url string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.Progress.Frontier, url)
+}
+
+// MarkVisited moves the given URL from the crawl frontier into the visited set.
+func (s *State) MarkVisited(url string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.Progress.Frontier, url)
+	s.Progress.Visited[url] = true
+}
+
+// AddChunkHashes records the hashes of the given indexed chunks.
+func (s *State) AddChunkHashes(hashes ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, h := range hashes {
+		s.Progress.ChunkHashes[h] = true
+	}
+}
@@ -0,0 +1,41 @@
+package crawler
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateProgress(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "state.json")
+	seedURL := "https://example.org/"
+
+	state, err := LoadState(file)
+	require.NoError(t, err)
+	require.False(t, state.Resumable(seedURL))
+
+	state.ResetProgress(seedURL)
+	require.True(t, state.AddToFrontier(seedURL, 1))
+	require.True(t, state.AddToFrontier("https://example.org/a", 2))
+	require.True(t, state.AddToFrontier("https://example.org/b", 2))
+	require.False(t, state.AddToFrontier("https://example.org/a", 3), "add pending URL")
+	state.MarkVisited(seedURL)
+	state.RemoveFromFrontier("https://example.org/b")
+	state.AddChunkHashes("hash")
+	require.False(t, state.AddToFrontier(seedURL, 1), "add visited URL")
+
+	err = state.Save(file)
+	require.NoError(t, err)
+
+	state, err = LoadState(file)
+	require.NoError(t, err)
+	require.True(t, state.Resumable(seedURL))
+	require.False(t, state.Resumable("https://example.org/other"))
+	require.Equal(t, map[string]int{"https://example.org/a": 2}, state.Frontier())
+	require.Equal(t, []string{seedURL}, state.VisitedURLs())
+	require.Equal(t, []string{"hash"}, state.ChunkHashes())
+
+	state.CompleteProgress()
+	require.False(t, state.Resumable(seedURL))
+}
@@ -0,0 +1,52 @@
+package directory
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// splitFrontMatter separates the YAML front matter from the given markdown document.
+// It returns the unchanged document and nil when the document does not start with a front matter block.
+func splitFrontMatter(doc string) (map[string]any, string, error) {
+	doc = strings.TrimPrefix(doc, "\ufeff")
+
+	if !strings.HasPrefix(doc, "---\n") && !strings.HasPrefix(doc, "---\r\n") {
+		return nil, doc, nil
+	}
+
+	rest := doc[strings.Index(doc, "\n")+1:]
+	end := -1
+	bodyStart := len(rest)
+
+	for offset := 0; offset < len(rest); {
+		lineEnd := strings.Index(rest[offset:], "\n")
+		if lineEnd < 0 {
+			lineEnd = len(rest) - offset
+		}
+
+		line := strings.TrimRight(rest[offset:offset+lineEnd], "\r")
+		if line == "---" || line == "..." {
+			end = offset
+			bodyStart = min(offset+lineEnd+1, len(rest))
+
+			break
+		}
+
+		offset += lineEnd + 1
+	}
+
+	if end < 0 {
+		return nil, doc, nil
+	}
+
+	frontMatter := map[string]any{}
+
+	err := yaml.Unmarshal([]byte(rest[:end]), &frontMatter)
+	if err != nil {
+		return nil, "", fmt.Errorf("parse front matter: %w", err)
+	}
+
+	return frontMatter, rest[bodyStart:], nil
+}
@@ -0,0 +1,53 @@
+package directory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	for _, tc := range []struct {
+		name              string
+		input             string
+		expectFrontMatter map[string]any
+		expectBody        string
+	}{
+		{
+			name:              "front matter",
+			input:             "---\ntitle: Getting started\ntags: [a, b]\n---\n# Intro\ntext",
+			expectFrontMatter: map[string]any{"title": "Getting started", "tags": []any{"a", "b"}},
+			expectBody:        "# Intro\ntext",
+		},
+		{
+			name:              "CRLF line endings",
+			input:             "---\r\ntitle: Windows\r\n---\r\nbody",
+			expectFrontMatter: map[string]any{"title": "Windows"},
+			expectBody:        "body",
+		},
+		{
+			name:              "empty body",
+			input:             "---\ntitle: Only front matter\n---",
+			expectFrontMatter: map[string]any{"title": "Only front matter"},
+			expectBody:        "",
+		},
+		{
+			name:       "no front matter",
+			input:      "# Title\n---\ntext",
+			expectBody: "# Title\n---\ntext",
+		},
+		{
+			name:       "unterminated front matter",
+			input:      "---\ntitle: x\n# Title",
+			expectBody: "---\ntitle: x\n# Title",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			frontMatter, body, err := splitFrontMatter(tc.input)
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expectFrontMatter, frontMatter, "front matter")
+			require.Equal(t, tc.expectBody, body, "body")
+		})
+	}
+}
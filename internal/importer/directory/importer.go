@@ -0,0 +1,164 @@
+package directory
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mgoltzsche/knowledgebot/internal/importer"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// contentTypes maps the supported file extensions to their content type.
+var contentTypes = map[string]string{
+	".md":       "text/markdown",
+	".markdown": "text/markdown",
+	".html":     "text/html",
+	".htm":      "text/html",
+	".xhtml":    "application/xhtml+xml",
+	".txt":      "text/plain",
+	".pdf":      "application/pdf",
+}
+
+// Importer imports the Markdown, HTML, plain text and PDF files within a directory into a vector store.
+type Importer struct {
+	importer.Chunker
+	importer.ContentParser
+	// BaseURL is the URL the file paths are resolved against in order to derive a document URL.
+	// When nil, file:// URLs are used.
+	BaseURL *url.URL
+	Sink    vectorstores.VectorStore
+}
+
+func (i *Importer) Import(ctx context.Context, dir string) error {
+	slog.Info("importing directory "+dir, "baseURL", i.BaseURL)
+
+	startTime := time.Now()
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	fileCount := 0
+	chunkCount := 0
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		contentType, ok := contentTypes[strings.ToLower(filepath.Ext(path))]
+		if !ok {
+			return nil
+		}
+
+		u, err := i.fileURL(dir, path)
+		if err != nil {
+			return err
+		}
+
+		chunks, err := i.loadFile(path, contentType, u)
+		if err != nil {
+			slog.Warn(fmt.Sprintf("skipping file %s: %s", path, err))
+			return nil
+		}
+
+		slog.Info(fmt.Sprintf("read %d chunks from %s", len(chunks), path))
+
+		err = importer.ReplaceDocuments(ctx, i.Sink, u.String(), chunks)
+		if err != nil {
+			return err
+		}
+
+		fileCount++
+		chunkCount += len(chunks)
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("import directory: %w", err)
+	}
+
+	elapsed := time.Since(startTime)
+
+	slog.Info(fmt.Sprintf("indexed %d chunks of %d file(s) in %s", chunkCount, fileCount, elapsed))
+
+	return nil
+}
+
+func (i *Importer) fileURL(dir, path string) (*url.URL, error) {
+	if i.BaseURL == nil {
+		return &url.URL{Scheme: "file", Path: filepath.ToSlash(path)}, nil
+	}
+
+	relPath, err := filepath.Rel(dir, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.BaseURL.JoinPath(filepath.ToSlash(relPath)), nil
+}
+
+func (i *Importer) loadFile(path, contentType string, u *url.URL) ([]schema.Document, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var frontMatter map[string]any
+
+	if contentType == "text/markdown" {
+		var markdown string
+
+		frontMatter, markdown, err = splitFrontMatter(string(b))
+		if err != nil {
+			return nil, err
+		}
+
+		b = []byte(markdown)
+	}
+
+	content, err := i.Parse(contentType, b)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := make(map[string]any, len(frontMatter)+2)
+
+	for k, v := range frontMatter {
+		metadata[k] = v
+	}
+
+	title, _ := frontMatter["title"].(string)
+	if title == "" {
+		title = content.DeriveTitle(u)
+	}
+
+	metadata["url"] = u.String()
+	metadata["title"] = title
+
+	delete(metadata, "content")
+	delete(metadata, "page")
+
+	return i.ChunkContent(content, metadata)
+}
@@ -0,0 +1,70 @@
+package directory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mgoltzsche/knowledgebot/internal/importer"
+	"github.com/mgoltzsche/knowledgebot/internal/localstore"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+)
+
+type countingEmbedder struct {
+	embedded int
+}
+
+func (e *countingEmbedder) EmbedDocuments(_ context.Context, texts []string) ([][]float32, error) {
+	e.embedded += len(texts)
+
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = []float32{1, 0}
+	}
+
+	return vectors, nil
+}
+
+func (e *countingEmbedder) EmbedQuery(_ context.Context, _ string) ([]float32, error) {
+	return []float32{1, 0}, nil
+}
+
+func TestImportReplacesStaleChunks(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	file := localstore.File(t.TempDir(), "test")
+	require.NoError(t, localstore.CreateIfNotExist(file))
+
+	embedder := &countingEmbedder{}
+	store, err := localstore.New(file, embedder)
+	require.NoError(t, err)
+
+	importDir := func(content string) {
+		err := os.WriteFile(filepath.Join(dir, "doc.md"), []byte(content), 0o644)
+		require.NoError(t, err)
+
+		i := &Importer{Chunker: importer.Chunker{ChunkSize: 20}, Sink: store}
+		err = i.Import(ctx, dir)
+		require.NoError(t, err)
+	}
+
+	importDir("first paragraph\n\nsecond paragraph")
+	require.Equal(t, 2, embedder.embedded, "initially embedded chunks")
+
+	importDir("first paragraph\n\nchanged paragraph")
+	require.Equal(t, 3, embedder.embedded, "only the changed chunk should be embedded")
+
+	var contents []string
+
+	err = store.ScrollDocuments(10, func(docs []schema.Document, _ [][]float32) error {
+		for _, d := range docs {
+			contents = append(contents, d.PageContent)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"first paragraph", "changed paragraph"}, contents)
+}
@@ -0,0 +1,188 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// alwaysExcludedSelector selects elements that never contain readable content.
+	alwaysExcludedSelector = "script, style, noscript, template, iframe, svg, canvas, head"
+	// boilerplateSelector selects elements that are removed when the main content is detected heuristically.
+	boilerplateSelector = "nav, aside, footer, form, dialog, [role=navigation], [role=banner], [role=contentinfo], [role=complementary], [role=search], [aria-hidden=true]"
+	// mainContentSelector selects elements that are known to contain a page's main content.
+	mainContentSelector = "main, [role=main], article"
+)
+
+// boilerplateRegex matches the class or id of an element that likely does not contain a page's main content.
+// It is only applied when the main content was not found within an explicit content element
+// since words such as "comments" or "menu" can also name a section of the actual content.
+var boilerplateRegex = regexp.MustCompile(`(?i)(^|[\s_-])(nav|navbox|navbar|navigation|sidebar|footer|breadcrumbs?|cookies?|consent|advert)($|[\s_-])`)
+
+// ValidateSelectors returns an error if any of the given CSS selectors is invalid.
+func ValidateSelectors(selectors []string) error {
+	for _, sel := range selectors {
+		_, err := cascadia.Compile(sel)
+		if err != nil {
+			return fmt.Errorf("invalid css selector %q: %w", sel, err)
+		}
+	}
+
+	return nil
+}
+
+// extractHTMLContent returns the HTML of the given document's main content.
+// It also returns the document's first H1 heading when it is not part of the main content
+// as well as the IDs of the main content's headings, mapped by heading title.
+func (p *ContentParser) extractHTMLContent(contentType string, body []byte) (string, string, map[string]string, error) {
+	r, err := charset.NewReader(bytes.NewReader(body), contentType)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("detect charset: %w", err)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("parse html: %w", err)
+	}
+
+	h1 := strings.TrimSpace(doc.Find("h1").First().Text())
+
+	doc.Find(alwaysExcludedSelector).Remove()
+
+	for _, sel := range p.ExcludeSelectors {
+		doc.Find(sel).Remove()
+	}
+
+	root, explicit := p.findMainContent(doc)
+
+	if p.MainContentDetection {
+		root.Find(boilerplateSelector).Remove()
+
+		if !explicit {
+			root.Find("[class], [id]").FilterFunction(func(_ int, s *goquery.Selection) bool {
+				return boilerplateRegex.MatchString(s.AttrOr("class", "")) || boilerplateRegex.MatchString(s.AttrOr("id", ""))
+			}).Remove()
+		}
+	}
+
+	if root.Find("h1").Length() > 0 {
+		h1 = ""
+	}
+
+	var buf strings.Builder
+
+	for _, n := range root.Nodes {
+		err = html.Render(&buf, n)
+		if err != nil {
+			return "", "", nil, fmt.Errorf("render html: %w", err)
+		}
+	}
+
+	return buf.String(), h1, headingAnchors(root), nil
+}
+
+// headingAnchors maps the titles of the headings within the given selection to their IDs.
+// Headings without an ID attribute inherit the ID of their first descendant that has one, e.g. a MediaWiki headline span.
+func headingAnchors(root *goquery.Selection) map[string]string {
+	var anchors map[string]string
+
+	root.Find("h1, h2, h3, h4, h5, h6").Each(func(_ int, s *goquery.Selection) {
+		id := s.AttrOr("id", "")
+		if id == "" {
+			id = s.Find("[id]").First().AttrOr("id", "")
+		}
+
+		title := normalizeHeading(s.Text())
+
+		if _, exists := anchors[title]; id == "" || title == "" || exists {
+			return
+		}
+
+		if anchors == nil {
+			anchors = map[string]string{}
+		}
+
+		anchors[title] = id
+	})
+
+	return anchors
+}
+
+// findMainContent returns the element containing the document's main content
+// and whether it was selected explicitly, either by a content selector or a main content element.
+func (p *ContentParser) findMainContent(doc *goquery.Document) (*goquery.Selection, bool) {
+	for _, sel := range p.ContentSelectors {
+		if s := doc.Find(sel); s.Length() > 0 {
+			return s, true
+		}
+	}
+
+	body := doc.Find("body")
+
+	if !p.MainContentDetection {
+		return body, false
+	}
+
+	if s := doc.Find(mainContentSelector).First(); s.Length() > 0 {
+		return s, true
+	}
+
+	if s := findDensestTextElement(body); s != nil {
+		return s, false
+	}
+
+	return body, false
+}
+
+// findDensestTextElement returns the element that holds most of the document's paragraph text, readability-style.
+// Each paragraph adds its text length to the score of its parent and half of it to the score of its grandparent.
+func findDensestTextElement(body *goquery.Selection) *goquery.Selection {
+	scores := map[*html.Node]int{}
+	totalLen := 0
+
+	body.Find("p, pre, td, blockquote").Each(func(_ int, s *goquery.Selection) {
+		textLen := len(strings.TrimSpace(s.Text()))
+		if textLen < 25 {
+			return
+		}
+
+		totalLen += textLen
+
+		parent := s.Parent()
+		if parent.Length() == 0 {
+			return
+		}
+
+		scores[parent.Nodes[0]] += textLen
+
+		if grandParent := parent.Parent(); grandParent.Length() > 0 {
+			scores[grandParent.Nodes[0]] += textLen / 2
+		}
+	})
+
+	var (
+		best      *html.Node
+		bestScore int
+	)
+
+	for n, score := range scores {
+		if score > bestScore {
+			best = n
+			bestScore = score
+		}
+	}
+
+	// Only use the candidate when it contains a significant part of the document's text.
+	if best == nil || bestScore < totalLen/3 {
+		return nil
+	}
+
+	return goquery.NewDocumentFromNode(best).Selection
+}
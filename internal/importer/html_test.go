@@ -0,0 +1,87 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPage = `<!DOCTYPE html>
+<html>
+<head><title>Page</title><style>p{}</style></head>
+<body>
+  <header><a href="/">Home</a> <a href="/about">About</a></header>
+  <div id="cookie-banner">We use cookies.</div>
+  <div class="layout">
+    <div class="sidebar"><ul><li>Menu item</li></ul></div>
+    <div class="content">
+      <h1>Planet Express</h1>
+      <p>Planet Express is a delivery company that is run by Professor Farnsworth.</p>
+      <p>Its crew delivers packages throughout the universe.</p>
+      <table class="navbox"><tr><td>Characters: Fry, Leela, Bender</td></tr></table>
+    </div>
+  </div>
+  <footer>Copyright</footer>
+</body>
+</html>`
+
+func TestContentParserHTML(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		parser ContentParser
+		input  string
+		expect Content
+	}{
+		{
+			name:   "main content detection",
+			parser: ContentParser{MainContentDetection: true},
+			input:  testPage,
+			expect: Content{Markdown: "# Planet Express\n\nPlanet Express is a delivery company that is run by Professor Farnsworth.\n\nIts crew delivers packages throughout the universe."},
+		},
+		{
+			name:   "main element",
+			parser: ContentParser{MainContentDetection: true},
+			input:  `<body><nav>Menu</nav><h1>Title</h1><main><p>Main text</p><aside>Aside</aside></main></body>`,
+			expect: Content{Title: "Title", Markdown: "Main text"},
+		},
+		{
+			name:   "main element section named like boilerplate",
+			parser: ContentParser{MainContentDetection: true},
+			input:  `<body><div class="menu">Menu</div><main><p>Main text</p><section id="comments"><p>Comments start with //.</p></section></main></body>`,
+			expect: Content{Markdown: "Main text\n\nComments start with //."},
+		},
+		{
+			name:   "content selector",
+			parser: ContentParser{ContentSelectors: []string{"#missing", ".sidebar"}},
+			input:  testPage,
+			expect: Content{Title: "Planet Express", Markdown: "- Menu item"},
+		},
+		{
+			name:   "exclude selector",
+			parser: ContentParser{ContentSelectors: []string{".content"}, ExcludeSelectors: []string{".navbox", "p:first-of-type"}},
+			input:  testPage,
+			expect: Content{Markdown: "# Planet Express\n\nIts crew delivers packages throughout the universe."},
+		},
+		{
+			name:   "heading anchors",
+			parser: ContentParser{},
+			input:  `<body><h2 id="Episodes">Episodes</h2><p>Text</p><h3><span class="mw-headline" id="Season_1">Season  1</span></h3><h3>Season 2</h3></body>`,
+			expect: Content{
+				Markdown: "## Episodes\n\nText\n\n### Season 1\n\n### Season 2",
+				Anchors:  map[string]string{"Episodes": "Episodes", "Season 1": "Season_1"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := tc.parser.Parse("text/html", []byte(tc.input))
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, *actual)
+		})
+	}
+}
+
+func TestValidateSelectors(t *testing.T) {
+	require.NoError(t, ValidateSelectors([]string{"main", ".navbox", "div#content > p"}))
+	require.Error(t, ValidateSelectors([]string{"main", "div["}))
+}
@@ -0,0 +1,193 @@
+// Package jsonl exports and imports document chunks as JSON Lines, allowing to back up knowledge bases and move them between environments.
+package jsonl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"time"
+
+	"github.com/mgoltzsche/knowledgebot/internal/qdrantutils"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// maxLineSize is the maximum size of a line within an import file.
+const maxLineSize = 16 << 20
+
+// Document is a line of an export file: a chunk along with its metadata and, optionally, its vector.
+type Document struct {
+	Content  string         `json:"content"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+	// EmbeddingModel is the name of the embedding model that produced the vector.
+	EmbeddingModel string    `json:"embeddingModel,omitempty"`
+	Vector         []float32 `json:"vector,omitempty"`
+}
+
+// VectorAdder is implemented by vector stores that can store documents along with precomputed vectors.
+type VectorAdder interface {
+	AddDocumentsWithVectors(ctx context.Context, docs []schema.Document, vectors [][]float32) ([]string, error)
+}
+
+// Encoder writes documents as JSON Lines.
+type Encoder struct {
+	enc *json.Encoder
+}
+
+// NewEncoder returns an encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	return &Encoder{enc: enc}
+}
+
+// Encode writes the given documents along with the vectors, if any.
+// The embedding model is taken from the documents' metadata (see qdrantutils.EmbeddingModelKey).
+func (e *Encoder) Encode(docs []schema.Document, vectors [][]float32) error {
+	for i, doc := range docs {
+		metadata := maps.Clone(doc.Metadata)
+		model, _ := metadata[qdrantutils.EmbeddingModelKey].(string)
+
+		delete(metadata, qdrantutils.EmbeddingModelKey)
+
+		d := Document{
+			Content:        doc.PageContent,
+			Metadata:       metadata,
+			EmbeddingModel: model,
+		}
+
+		if i < len(vectors) {
+			d.Vector = vectors[i]
+		}
+
+		err := e.enc.Encode(d)
+		if err != nil {
+			return fmt.Errorf("write document: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Importer imports the documents of a JSON Lines file into a vector store.
+// The vectors of documents that were produced by the store's embedding model are reused, all other documents are embedded.
+type Importer struct {
+	Sink vectorstores.VectorStore
+	// EmbeddingModel is the name of the store's embedding model.
+	EmbeddingModel string
+	// BatchSize is the number of documents that are added to the store at once.
+	BatchSize int
+}
+
+// Import reads the documents from r and adds them to the store.
+func (i *Importer) Import(ctx context.Context, r io.Reader) error {
+	startTime := time.Now()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	var (
+		batch              []Document
+		count, reusedCount int
+	)
+
+	flush := func() error {
+		reused, err := i.add(ctx, batch)
+		if err != nil {
+			return err
+		}
+
+		count += len(batch)
+		reusedCount += reused
+		batch = batch[:0]
+
+		slog.Info(fmt.Sprintf("imported %d documents", count))
+
+		return nil
+	}
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var doc Document
+
+		err := json.Unmarshal(scanner.Bytes(), &doc)
+		if err != nil {
+			return fmt.Errorf("parse line %d: %w", line, err)
+		}
+
+		if doc.Content == "" {
+			return fmt.Errorf("parse line %d: document has no content", line)
+		}
+
+		batch = append(batch, doc)
+
+		if len(batch) >= max(i.BatchSize, 1) {
+			err = flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return fmt.Errorf("read documents: %w", err)
+	}
+
+	if len(batch) > 0 {
+		err = flush()
+		if err != nil {
+			return err
+		}
+	}
+
+	slog.Info(fmt.Sprintf("imported %d documents (%d without embedding them again) in %s", count, reusedCount, time.Since(startTime)))
+
+	return nil
+}
+
+// add adds the given documents to the store and returns the number of documents whose vectors were reused.
+func (i *Importer) add(ctx context.Context, docs []Document) (int, error) {
+	var (
+		embedDocs    []schema.Document
+		reuseDocs    []schema.Document
+		reuseVectors [][]float32
+	)
+
+	adder, canReuse := i.Sink.(VectorAdder)
+
+	for _, d := range docs {
+		doc := schema.Document{PageContent: d.Content, Metadata: d.Metadata}
+
+		if canReuse && len(d.Vector) > 0 && d.EmbeddingModel != "" && d.EmbeddingModel == i.EmbeddingModel {
+			reuseDocs = append(reuseDocs, doc)
+			reuseVectors = append(reuseVectors, d.Vector)
+		} else {
+			embedDocs = append(embedDocs, doc)
+		}
+	}
+
+	if len(reuseDocs) > 0 {
+		_, err := adder.AddDocumentsWithVectors(ctx, reuseDocs, reuseVectors)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if len(embedDocs) > 0 {
+		_, err := i.Sink.AddDocuments(ctx, embedDocs)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(reuseDocs), nil
+}
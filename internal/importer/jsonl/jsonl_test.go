@@ -0,0 +1,67 @@
+package jsonl
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+type fakeStore struct {
+	embedded []schema.Document
+	reused   []schema.Document
+	vectors  [][]float32
+}
+
+func (s *fakeStore) AddDocuments(_ context.Context, docs []schema.Document, _ ...vectorstores.Option) ([]string, error) {
+	s.embedded = append(s.embedded, docs...)
+	return nil, nil
+}
+
+func (s *fakeStore) AddDocumentsWithVectors(_ context.Context, docs []schema.Document, vectors [][]float32) ([]string, error) {
+	s.reused = append(s.reused, docs...)
+	s.vectors = append(s.vectors, vectors...)
+
+	return nil, nil
+}
+
+func (s *fakeStore) SimilaritySearch(context.Context, string, int, ...vectorstores.Option) ([]schema.Document, error) {
+	return nil, nil
+}
+
+func TestExportImport(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := NewEncoder(&buf).Encode([]schema.Document{
+		{PageContent: "chunk a", Metadata: map[string]any{"url": "https://example.org/a", "embedding_model": "all-minilm"}},
+		{PageContent: "chunk b", Metadata: map[string]any{"url": "https://example.org/b", "embedding_model": "nomic-embed-text"}},
+	}, [][]float32{{0.5, 1}, {1, 0}})
+	require.NoError(t, err)
+
+	err = NewEncoder(&buf).Encode([]schema.Document{
+		{PageContent: "chunk c", Metadata: map[string]any{"url": "https://example.org/c", "embedding_model": "all-minilm"}},
+	}, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, `{"content":"chunk a","metadata":{"url":"https://example.org/a"},"embeddingModel":"all-minilm","vector":[0.5,1]}
+{"content":"chunk b","metadata":{"url":"https://example.org/b"},"embeddingModel":"nomic-embed-text","vector":[1,0]}
+{"content":"chunk c","metadata":{"url":"https://example.org/c"},"embeddingModel":"all-minilm"}
+`, buf.String())
+
+	store := &fakeStore{}
+	importer := &Importer{Sink: store, EmbeddingModel: "all-minilm", BatchSize: 2}
+
+	err = importer.Import(context.Background(), &buf)
+	require.NoError(t, err)
+	require.Equal(t, []schema.Document{
+		{PageContent: "chunk a", Metadata: map[string]any{"url": "https://example.org/a"}},
+	}, store.reused, "documents with vectors of the same model")
+	require.Equal(t, [][]float32{{0.5, 1}}, store.vectors)
+	require.Equal(t, []schema.Document{
+		{PageContent: "chunk b", Metadata: map[string]any{"url": "https://example.org/b"}},
+		{PageContent: "chunk c", Metadata: map[string]any{"url": "https://example.org/c"}},
+	}, store.embedded, "documents with vectors of another model or without vectors")
+}
@@ -1,4 +1,4 @@
-package crawler
+package importer
 
 import (
 	"regexp"
@@ -8,7 +8,7 @@ import (
 var markdownLinkRegex = regexp.MustCompile(`!?\[([^]]*)\]\([^)]+\)`)
 var whitespaceRegex = regexp.MustCompile(` +`)
 
-func stripMarkdownLinks(markdown string) string {
+func StripMarkdownLinks(markdown string) string {
 	markdown = markdownLinkRegex.ReplaceAllString(markdown, " $1 ")
 	markdown = whitespaceRegex.ReplaceAllString(markdown, " ")
 	markdown = strings.TrimSpace(markdown)
@@ -1,4 +1,4 @@
-package crawler
+package importer
 
 import (
 	"testing"
@@ -34,7 +34,7 @@ func TestStripMarkdownLinks(t *testing.T) {
 		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
-			actual := stripMarkdownLinks(tc.input)
+			actual := StripMarkdownLinks(tc.input)
 
 			require.Equal(t, tc.expect, actual)
 		})
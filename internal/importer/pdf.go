@@ -0,0 +1,48 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+var horizontalWhitespaceRegex = regexp.MustCompile(`[ \t]+`)
+
+func parsePDF(body []byte) (c *Content, err error) {
+	defer func() {
+		// The pdf library panics on some malformed documents.
+		if r := recover(); r != nil {
+			err = fmt.Errorf("parse pdf: %v", r)
+		}
+	}()
+
+	r, err := pdf.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, fmt.Errorf("parse pdf: %w", err)
+	}
+
+	numPages := r.NumPage()
+	c = &Content{
+		Title: strings.TrimSpace(r.Trailer().Key("Info").Key("Title").Text()),
+		Pages: make([]string, numPages),
+	}
+
+	for i := range numPages {
+		page := r.Page(i + 1)
+		if page.V.IsNull() {
+			continue
+		}
+
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			return nil, fmt.Errorf("extract text of pdf page %d: %w", i+1, err)
+		}
+
+		c.Pages[i] = strings.TrimSpace(horizontalWhitespaceRegex.ReplaceAllString(text, " "))
+	}
+
+	return c, nil
+}
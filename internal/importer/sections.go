@@ -0,0 +1,114 @@
+package importer
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	headingRegex          = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$`)
+	fenceRegex            = regexp.MustCompile("^ {0,3}(```|~~~)")
+	headingMarkupReplacer = strings.NewReplacer("*", "", "_", "", "`", "", "\\", "")
+)
+
+// section is a part of a markdown document below a heading.
+type section struct {
+	// path holds the titles of the section's heading and its parent headings.
+	path []string
+	// anchor is the fragment that links to the section's heading.
+	anchor string
+	text   string
+}
+
+// splitSections splits the given markdown document at its headings.
+// The anchors map heading titles to HTML element IDs.
+// When a heading is not present within the map, a GitHub-style anchor is derived from its title.
+func splitSections(markdown string, anchors map[string]string) []section {
+	var (
+		sections []section
+		headings []string
+		anchor   string
+		text     strings.Builder
+		fence    string
+	)
+
+	flush := func() {
+		if strings.TrimSpace(text.String()) != "" {
+			sections = append(sections, section{
+				path:   append([]string(nil), headings...),
+				anchor: anchor,
+				text:   strings.TrimSpace(text.String()),
+			})
+		}
+
+		text.Reset()
+	}
+
+	for _, line := range strings.Split(markdown, "\n") {
+		if m := fenceRegex.FindStringSubmatch(line); m != nil {
+			if fence == "" {
+				fence = m[1]
+			} else if fence == m[1] {
+				fence = ""
+			}
+		} else if m := headingRegex.FindStringSubmatch(line); m != nil && fence == "" {
+			title := normalizeHeading(m[2])
+			if title != "" {
+				flush()
+
+				level := len(m[1])
+				for len(headings) < level-1 {
+					headings = append(headings, "")
+				}
+
+				headings = append(headings[:level-1], title)
+
+				anchor = anchors[title]
+				if anchor == "" {
+					anchor = slugify(title)
+				}
+			}
+		}
+
+		text.WriteString(line)
+		text.WriteString("\n")
+	}
+
+	flush()
+
+	return sections
+}
+
+// headingPath returns the section's non-empty heading titles, joined by " > ".
+func (s *section) headingPath() string {
+	path := make([]string, 0, len(s.path))
+
+	for _, h := range s.path {
+		if h != "" {
+			path = append(path, h)
+		}
+	}
+
+	return strings.Join(path, " > ")
+}
+
+func normalizeHeading(title string) string {
+	return strings.Join(strings.Fields(headingMarkupReplacer.Replace(title)), " ")
+}
+
+// slugify derives a GitHub-style anchor from a heading title.
+func slugify(title string) string {
+	var b strings.Builder
+
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+
+	return b.String()
+}
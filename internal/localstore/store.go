@@ -0,0 +1,679 @@
+// Package localstore provides an in-process vector store that persists documents within a local file.
+// It allows running the knowledge bot without a Qdrant server, e.g. on a laptop.
+package localstore
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mgoltzsche/knowledgebot/internal/importer"
+	"github.com/mgoltzsche/knowledgebot/internal/qdrantutils"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// FileExtension is the extension of the files the documents of a collection are stored in.
+const FileExtension = ".jsonl"
+
+// compactionThreshold is the minimum number of obsolete entries within the store file that triggers its compaction.
+const compactionThreshold = 1000
+
+// ErrCollectionNotFound is returned when the store file does not exist.
+var ErrCollectionNotFound = errors.New("collection not found")
+
+// Store is a vector store that keeps all documents in memory and persists them within an append-only log file.
+// The file must be written by a single process at a time but other processes can read it concurrently:
+// before every operation the store applies the entries that were appended to the file in the meantime.
+// When the file contains many obsolete entries, the writing process compacts it.
+type Store struct {
+	// EmbeddingModel is stored within the metadata of added documents in order to detect when the store is used with another model.
+	EmbeddingModel string
+	file           string
+	embedder       embeddings.Embedder
+	mutex          sync.Mutex
+	records        map[string]*record
+	// offset is the number of bytes of the file that were applied to records.
+	offset int64
+	// entries is the number of entries within the file.
+	entries int
+	// fileInfo identifies the file that was read last, in order to detect when it was replaced by compaction.
+	fileInfo os.FileInfo
+}
+
+var _ vectorstores.VectorStore = &Store{}
+
+type record struct {
+	ID       string         `json:"id"`
+	Content  string         `json:"content"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+	Vector   []float32      `json:"vector"`
+	norm     float64
+}
+
+// entry is a line within the store file: either a document that is added or a URL whose documents are deleted.
+type entry struct {
+	Record    *record `json:"record,omitempty"`
+	DeleteURL string  `json:"deleteURL,omitempty"`
+	// KeepIDs are the IDs of the documents that are not deleted along with the URL's other documents.
+	KeepIDs []string `json:"keepIDs,omitempty"`
+}
+
+// File returns the path of the file the given collection is stored in within the given directory.
+func File(dir, collection string) string {
+	return filepath.Join(dir, collection+FileExtension)
+}
+
+// List returns the names of the collections stored within the given directory, sorted alphabetically.
+func List(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("list local store collections: %w", err)
+	}
+
+	var names []string
+
+	for _, f := range files {
+		if name, ok := strings.CutSuffix(f.Name(), FileExtension); ok && !f.IsDir() {
+			names = append(names, name)
+		}
+	}
+
+	slices.Sort(names)
+
+	return names, nil
+}
+
+// CreateIfNotExist creates the file of a store if it does not exist.
+func CreateIfNotExist(file string) error {
+	err := os.MkdirAll(filepath.Dir(file), 0o755)
+	if err != nil {
+		return fmt.Errorf("create local store directory: %w", err)
+	}
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("create local store: %w", err)
+	}
+
+	return f.Close()
+}
+
+// New loads the store from the given file.
+// It returns ErrCollectionNotFound if the file does not exist.
+func New(file string, embedder embeddings.Embedder) (*Store, error) {
+	s := &Store{
+		file:     file,
+		embedder: embedder,
+		records:  map[string]*record{},
+	}
+
+	err := s.sync()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// AddDocuments embeds and stores the given documents.
+// Documents are identified by their URL and content (see qdrantutils.PointID), documents that exist already are not embedded again
+// but their metadata is updated when it changed.
+func (s *Store) AddDocuments(ctx context.Context, docs []schema.Document, _ ...vectorstores.Option) ([]string, error) {
+	return s.addDocuments(ctx, docs, nil)
+}
+
+// AddDocumentsWithVectors stores the given documents along with their precomputed vectors instead of embedding them.
+// The vectors must have been produced by the store's embedding model.
+func (s *Store) AddDocumentsWithVectors(ctx context.Context, docs []schema.Document, vectors [][]float32) ([]string, error) {
+	if len(vectors) != len(docs) {
+		return nil, errors.New("number of vectors does not match number of documents")
+	}
+
+	return s.addDocuments(ctx, docs, vectors)
+}
+
+// addDocuments stores the documents that do not exist within the store, embedding them unless vectors are provided.
+// The metadata of existing documents is updated when it differs.
+func (s *Store) addDocuments(ctx context.Context, docs []schema.Document, vectors [][]float32) ([]string, error) {
+	ids := make([]string, len(docs))
+	newDocs := make([]schema.Document, 0, len(docs))
+	newIDs := make([]string, 0, len(docs))
+	newVectors := make([][]float32, 0, len(docs))
+	seen := make(map[string]bool, len(docs))
+
+	var entries []entry
+
+	s.mutex.Lock()
+
+	err := s.sync()
+	if err != nil {
+		s.mutex.Unlock()
+		return nil, err
+	}
+
+	for i, doc := range docs {
+		id := qdrantutils.PointID(doc)
+		ids[i] = id
+
+		if seen[id] {
+			continue
+		}
+
+		seen[id] = true
+
+		if r, ok := s.records[id]; ok {
+			metadata := s.metadata(doc)
+			if !importer.EqualMetadata(r.Metadata, metadata) {
+				entries = append(entries, entry{Record: &record{ID: id, Content: r.Content, Metadata: metadata, Vector: r.Vector}})
+			}
+		} else {
+			newDocs = append(newDocs, doc)
+			newIDs = append(newIDs, id)
+
+			if vectors != nil {
+				newVectors = append(newVectors, vectors[i])
+			}
+		}
+	}
+
+	s.mutex.Unlock()
+
+	if len(newDocs) == 0 && len(entries) == 0 {
+		return ids, nil
+	}
+
+	if vectors == nil && len(newDocs) > 0 {
+		texts := make([]string, len(newDocs))
+		for i, doc := range newDocs {
+			texts[i] = doc.PageContent
+		}
+
+		newVectors, err = s.embedder.EmbedDocuments(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(newVectors) != len(newDocs) {
+			return nil, errors.New("number of vectors from embedder does not match number of documents")
+		}
+	}
+
+	for i, doc := range newDocs {
+		entries = append(entries, entry{Record: &record{
+			ID:       newIDs[i],
+			Content:  doc.PageContent,
+			Metadata: s.metadata(doc),
+			Vector:   newVectors[i],
+		}})
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err = s.sync()
+	if err != nil {
+		return nil, err
+	}
+
+	dims := s.dimensions()
+	if dims == 0 && len(newVectors) > 0 {
+		dims = len(newVectors[0])
+	}
+
+	for _, v := range newVectors {
+		if len(v) != dims {
+			return nil, fmt.Errorf("cannot add a vector of size %d to the local store that contains vectors of size %d", len(v), dims)
+		}
+	}
+
+	err = s.append(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// metadata returns the metadata the given document is stored with.
+func (s *Store) metadata(doc schema.Document) map[string]any {
+	if s.EmbeddingModel == "" {
+		return doc.Metadata
+	}
+
+	metadata := maps.Clone(doc.Metadata)
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	metadata[qdrantutils.EmbeddingModelKey] = s.EmbeddingModel
+
+	return metadata
+}
+
+// DeleteDocumentsByURL deletes all documents that were stored for the given URL.
+func (s *Store) DeleteDocumentsByURL(ctx context.Context, u string) error {
+	return s.DeleteDocumentsByURLExcept(ctx, u, nil)
+}
+
+// DeleteDocumentsByURLExcept deletes the documents that were stored for the given URL, except the documents with the given IDs.
+func (s *Store) DeleteDocumentsByURLExcept(_ context.Context, u string, keepIDs []string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := s.sync()
+	if err != nil {
+		return err
+	}
+
+	return s.append([]entry{{DeleteURL: u, KeepIDs: keepIDs}})
+}
+
+// SimilaritySearch returns the documents that are most similar to the query by cosine similarity.
+// It supports the score threshold option as well as a filter that is specified as map of metadata values the documents must match.
+func (s *Store) SimilaritySearch(ctx context.Context, query string, numDocuments int, options ...vectorstores.Option) ([]schema.Document, error) {
+	opts := vectorstores.Options{}
+	for _, o := range options {
+		o(&opts)
+	}
+
+	var filter map[string]any
+
+	if opts.Filters != nil {
+		var ok bool
+
+		filter, ok = opts.Filters.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unsupported filter type %T, the local store supports map[string]any filters only", opts.Filters)
+		}
+	}
+
+	vector, err := s.embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+
+	queryNorm := norm(vector)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err = s.sync()
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]schema.Document, 0, numDocuments)
+
+	for _, r := range s.records {
+		if !matches(r.Metadata, filter) {
+			continue
+		}
+
+		score := cosineSimilarity(vector, queryNorm, r.Vector, r.norm)
+		if score < opts.ScoreThreshold {
+			continue
+		}
+
+		docs = append(docs, schema.Document{
+			PageContent: r.Content,
+			Metadata:    r.Metadata,
+			Score:       score,
+		})
+	}
+
+	slices.SortFunc(docs, func(a, b schema.Document) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+
+	return docs[:min(numDocuments, len(docs))], nil
+}
+
+// ScrollDocuments calls fn with batches of the stored documents along with their vectors, ordered by ID,
+// until all documents were passed or fn returns an error.
+// The documents' metadata contains the embedding model (see qdrantutils.EmbeddingModelKey).
+func (s *Store) ScrollDocuments(batchSize int, fn func(docs []schema.Document, vectors [][]float32) error) error {
+	s.mutex.Lock()
+
+	err := s.sync()
+	if err != nil {
+		s.mutex.Unlock()
+		return err
+	}
+
+	records := slices.SortedFunc(maps.Values(s.records), func(a, b *record) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	s.mutex.Unlock()
+
+	for batch := range slices.Chunk(records, batchSize) {
+		docs := make([]schema.Document, len(batch))
+		vectors := make([][]float32, len(batch))
+
+		for i, r := range batch {
+			docs[i] = schema.Document{PageContent: r.Content, Metadata: maps.Clone(r.Metadata)}
+			vectors[i] = r.Vector
+		}
+
+		err = fn(docs, vectors)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Count returns the number of documents within the store.
+func (s *Store) Count() (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := s.sync()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(s.records), nil
+}
+
+// Dimensions returns the size of the stored vectors or 0 if the store is empty.
+func (s *Store) Dimensions() (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := s.sync()
+	if err != nil {
+		return 0, err
+	}
+
+	return s.dimensions(), nil
+}
+
+// EmbeddingModels returns the names of the embedding models that produced the stored vectors, sorted alphabetically.
+func (s *Store) EmbeddingModels() ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := s.sync()
+	if err != nil {
+		return nil, err
+	}
+
+	models := map[string]struct{}{}
+
+	for _, r := range s.records {
+		if model, _ := r.Metadata[qdrantutils.EmbeddingModelKey].(string); model != "" {
+			models[model] = struct{}{}
+		}
+	}
+
+	return slices.Sorted(maps.Keys(models)), nil
+}
+
+func (s *Store) dimensions() int {
+	for _, r := range s.records {
+		return len(r.Vector)
+	}
+
+	return 0
+}
+
+// sync applies the entries that were appended to the file since it was read last.
+// When the file was compacted by another process in the meantime, it is read completely.
+func (s *Store) sync() error {
+	f, err := os.Open(s.file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrCollectionNotFound, s.file)
+		}
+
+		return fmt.Errorf("open local store: %w", err)
+	}
+
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("open local store: %w", err)
+	}
+
+	if s.fileInfo != nil && !os.SameFile(s.fileInfo, fi) {
+		// The file was compacted by another process.
+		s.records = map[string]*record{}
+		s.offset = 0
+		s.entries = 0
+	}
+
+	s.fileInfo = fi
+
+	if fi.Size() == s.offset {
+		return nil
+	}
+
+	_, err = f.Seek(s.offset, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("read local store: %w", err)
+	}
+
+	reader := bufio.NewReader(f)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Ignore an incomplete last line that is still being written.
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("read local store: %w", err)
+		}
+
+		var e entry
+
+		err = json.Unmarshal(line, &e)
+		if err != nil {
+			return fmt.Errorf("read local store %s at offset %d: %w", s.file, s.offset, err)
+		}
+
+		s.apply(e)
+		s.offset += int64(len(line))
+		s.entries++
+	}
+}
+
+func (s *Store) apply(e entry) {
+	if e.DeleteURL != "" {
+		for id, r := range s.records {
+			if u, _ := r.Metadata["url"].(string); u == e.DeleteURL && !slices.Contains(e.KeepIDs, id) {
+				delete(s.records, id)
+			}
+		}
+
+		return
+	}
+
+	if e.Record != nil {
+		e.Record.norm = norm(e.Record.Vector)
+		s.records[e.Record.ID] = e.Record
+	}
+}
+
+// append writes the given entries to the file and applies them.
+func (s *Store) append(entries []entry) error {
+	f, err := os.OpenFile(s.file, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrCollectionNotFound, s.file)
+		}
+
+		return fmt.Errorf("open local store: %w", err)
+	}
+
+	defer f.Close()
+
+	var buf []byte
+
+	for _, e := range entries {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("marshal local store entry: %w", err)
+		}
+
+		buf = append(append(buf, b...), '\n')
+	}
+
+	_, err = f.Write(buf)
+	if err != nil {
+		return fmt.Errorf("write local store: %w", err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("write local store: %w", err)
+	}
+
+	for _, e := range entries {
+		s.apply(e)
+	}
+
+	s.offset += int64(len(buf))
+	s.entries += len(entries)
+
+	// Since only the writing process compacts the file, readers can detect the replaced file reliably.
+	if s.entries > 2*len(s.records)+compactionThreshold {
+		return s.compact()
+	}
+
+	return nil
+}
+
+// compact rewrites the file so that it contains the current documents only.
+func (s *Store) compact() error {
+	tmpFile := s.file + ".tmp"
+
+	f, err := os.Create(tmpFile)
+	if err != nil {
+		return fmt.Errorf("compact local store: %w", err)
+	}
+
+	defer os.Remove(tmpFile)
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	size := int64(0)
+
+	for _, r := range s.records {
+		b, err := json.Marshal(entry{Record: r})
+		if err != nil {
+			return fmt.Errorf("compact local store: %w", err)
+		}
+
+		n, err := w.Write(append(b, '\n'))
+		if err != nil {
+			return fmt.Errorf("compact local store: %w", err)
+		}
+
+		size += int64(n)
+	}
+
+	err = w.Flush()
+	if err == nil {
+		err = f.Close()
+	}
+
+	if err == nil {
+		err = os.Rename(tmpFile, s.file)
+	}
+
+	if err != nil {
+		return fmt.Errorf("compact local store: %w", err)
+	}
+
+	fi, err := os.Stat(s.file)
+	if err != nil {
+		return fmt.Errorf("compact local store: %w", err)
+	}
+
+	s.fileInfo = fi
+	s.offset = size
+	s.entries = len(s.records)
+
+	return nil
+}
+
+// matches returns true if the given metadata contains all of the filter's values.
+func matches(metadata, filter map[string]any) bool {
+	for k, v := range filter {
+		if !equal(metadata[k], v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// equal compares metadata values, treating numbers of different types as equal if they have the same value.
+// This is necessary since numbers are float64 after unmarshalling the metadata from JSON.
+func equal(a, b any) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+
+	return a == b
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+func norm(v []float32) float64 {
+	sum := 0.0
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+
+	return math.Sqrt(sum)
+}
+
+func cosineSimilarity(a []float32, normA float64, b []float32, normB float64) float32 {
+	if len(a) != len(b) || normA == 0 || normB == 0 {
+		return 0
+	}
+
+	dot := 0.0
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+
+	return float32(dot / (normA * normB))
+}
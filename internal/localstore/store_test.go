@@ -0,0 +1,181 @@
+package localstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// fakeEmbedder maps the words "cat", "dog" and "fish" to orthogonal vectors.
+type fakeEmbedder struct {
+	calls int
+}
+
+func (e *fakeEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+
+	vectors := make([][]float32, len(texts))
+	for i, t := range texts {
+		vectors[i], _ = e.EmbedQuery(ctx, t)
+	}
+
+	return vectors, nil
+}
+
+func (e *fakeEmbedder) EmbedQuery(_ context.Context, text string) ([]float32, error) {
+	switch text {
+	case "cat":
+		return []float32{1, 0, 0}, nil
+	case "dog":
+		return []float32{0, 1, 0}, nil
+	case "cat and dog":
+		return []float32{1, 1, 0}, nil
+	default:
+		return []float32{0, 0, 1}, nil
+	}
+}
+
+func doc(text, url string) schema.Document {
+	return schema.Document{PageContent: text, Metadata: map[string]any{"url": url, "page": 1}}
+}
+
+func contents(docs []schema.Document) []string {
+	texts := make([]string, len(docs))
+	for i, d := range docs {
+		texts[i] = d.PageContent
+	}
+
+	return texts
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	file := File(t.TempDir(), "test")
+
+	_, err := New(file, &fakeEmbedder{})
+	require.ErrorIs(t, err, ErrCollectionNotFound)
+
+	err = CreateIfNotExist(file)
+	require.NoError(t, err)
+
+	embedder := &fakeEmbedder{}
+	store, err := New(file, embedder)
+	require.NoError(t, err)
+
+	store.EmbeddingModel = "all-minilm"
+
+	_, err = store.AddDocuments(ctx, []schema.Document{
+		doc("cat", "https://example.org/a"),
+		doc("cat and dog", "https://example.org/a"),
+		doc("dog", "https://example.org/b"),
+		doc("fish", "https://example.org/c"),
+	})
+	require.NoError(t, err)
+
+	_, err = store.AddDocuments(ctx, []schema.Document{doc("cat", "https://example.org/a")})
+	require.NoError(t, err)
+	require.Equal(t, 1, embedder.calls, "existing documents should not be embedded again")
+
+	renamed := doc("fish", "https://example.org/c")
+	renamed.Metadata["title"] = "Fish"
+
+	_, err = store.AddDocuments(ctx, []schema.Document{renamed})
+	require.NoError(t, err)
+	require.Equal(t, 1, embedder.calls, "documents with changed metadata should not be embedded again")
+
+	docs, err := store.SimilaritySearch(ctx, "fish", 1)
+	require.NoError(t, err)
+	require.Equal(t, "Fish", docs[0].Metadata["title"], "metadata should be updated")
+
+	count, err := store.Count()
+	require.NoError(t, err)
+	require.Equal(t, 4, count)
+
+	models, err := store.EmbeddingModels()
+	require.NoError(t, err)
+	require.Equal(t, []string{"all-minilm"}, models)
+
+	docs, err = store.SimilaritySearch(ctx, "cat", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"cat", "cat and dog"}, contents(docs))
+	require.InDelta(t, 1, docs[0].Score, 0.0001)
+	require.InDelta(t, 0.7071, docs[1].Score, 0.0001)
+
+	docs, err = store.SimilaritySearch(ctx, "cat", 10, vectorstores.WithScoreThreshold(0.5))
+	require.NoError(t, err)
+	require.Equal(t, []string{"cat", "cat and dog"}, contents(docs))
+
+	docs, err = store.SimilaritySearch(ctx, "dog", 10, vectorstores.WithFilters(map[string]any{"url": "https://example.org/b", "page": 1.0}))
+	require.NoError(t, err)
+	require.Equal(t, []string{"dog"}, contents(docs))
+
+	err = store.DeleteDocumentsByURL(ctx, "https://example.org/a")
+	require.NoError(t, err)
+
+	docs, err = store.SimilaritySearch(ctx, "cat", 10, vectorstores.WithScoreThreshold(0.5))
+	require.NoError(t, err)
+	require.Empty(t, docs)
+
+	reopened, err := New(file, embedder)
+	require.NoError(t, err)
+
+	docs, err = reopened.SimilaritySearch(ctx, "cat", 10)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"dog", "fish"}, contents(docs))
+	require.Equal(t, float64(1), docs[0].Metadata["page"], "metadata should be persisted")
+
+	_, err = store.AddDocuments(ctx, []schema.Document{doc("cat", "https://example.org/d")})
+	require.NoError(t, err)
+
+	docs, err = reopened.SimilaritySearch(ctx, "cat", 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"cat"}, contents(docs), "documents added by another store instance should be visible")
+}
+
+func TestStoreDimensionMismatch(t *testing.T) {
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "test.jsonl")
+	err := os.WriteFile(file, []byte(`{"record":{"id":"x","content":"x","vector":[1,0]}}`+"\n"), 0o644)
+	require.NoError(t, err)
+
+	store, err := New(file, &fakeEmbedder{})
+	require.NoError(t, err)
+
+	_, err = store.AddDocuments(ctx, []schema.Document{doc("cat", "https://example.org/a")})
+	require.Error(t, err)
+}
+
+func TestStoreCompaction(t *testing.T) {
+	ctx := context.Background()
+	file := File(t.TempDir(), "test")
+	err := CreateIfNotExist(file)
+	require.NoError(t, err)
+
+	store, err := New(file, &fakeEmbedder{})
+	require.NoError(t, err)
+
+	reader, err := New(file, &fakeEmbedder{})
+	require.NoError(t, err)
+
+	for range compactionThreshold {
+		_, err = store.AddDocuments(ctx, []schema.Document{doc("cat", "https://example.org/a")})
+		require.NoError(t, err)
+
+		err = store.DeleteDocumentsByURL(ctx, "https://example.org/a")
+		require.NoError(t, err)
+	}
+
+	_, err = store.AddDocuments(ctx, []schema.Document{doc("dog", "https://example.org/b")})
+	require.NoError(t, err)
+
+	require.Less(t, store.entries, compactionThreshold, "file should have been compacted")
+
+	count, err := reader.Count()
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+}
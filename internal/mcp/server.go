@@ -0,0 +1,305 @@
+// Package mcp implements a Model Context Protocol server that exposes tools via the stdio and streamable HTTP transports.
+// See https://modelcontextprotocol.io/specification
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// LatestProtocolVersion is the latest MCP version supported by the server.
+const LatestProtocolVersion = "2025-06-18"
+
+var supportedProtocolVersions = []string{LatestProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// Server handles MCP requests.
+type Server struct {
+	Name    string
+	Version string
+	mutex   sync.RWMutex
+	tools   []Tool
+}
+
+// Tool is a function that can be called by the client.
+type Tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// InputSchema is the JSON schema of the tool's arguments.
+	InputSchema map[string]any `json:"inputSchema"`
+	// Handler is called with the tool's arguments.
+	// A returned error is reported to the client as tool result with IsError set.
+	Handler func(ctx context.Context, args json.RawMessage) (*ToolResult, error) `json:"-"`
+}
+
+// ToolResult is the result of a tool call.
+type ToolResult struct {
+	Content []Content `json:"content"`
+	// StructuredContent optionally holds the result as JSON object.
+	StructuredContent any  `json:"structuredContent,omitempty"`
+	IsError           bool `json:"isError,omitempty"`
+}
+
+// Content is a part of a tool result.
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// TextResult returns a tool result containing the given text.
+func TextResult(text string) *ToolResult {
+	return &ToolResult{Content: []Content{{Type: "text", Text: text}}}
+}
+
+// ErrorResult returns a tool result that reports the given error to the model.
+func ErrorResult(err error) *ToolResult {
+	r := TextResult(err.Error())
+	r.IsError = true
+
+	return r
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// AddTool registers the given tool.
+func (s *Server) AddTool(t Tool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.tools = append(s.tools, t)
+}
+
+// HandleMessage handles a JSON-RPC message or batch of messages and returns the response.
+// It returns nil if the message does not require a response, e.g. when it is a notification.
+func (s *Server) HandleMessage(ctx context.Context, msg []byte) []byte {
+	msg = bytes.TrimSpace(msg)
+
+	if len(msg) > 0 && msg[0] == '[' {
+		var batch []json.RawMessage
+
+		err := json.Unmarshal(msg, &batch)
+		if err != nil {
+			return marshalResponse(errorResponse(nil, &rpcError{Code: codeParseError, Message: "parse error: " + err.Error()}))
+		}
+
+		responses := make([]*response, 0, len(batch))
+
+		for _, m := range batch {
+			if resp := s.handle(ctx, m); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+
+		if len(responses) == 0 {
+			return nil
+		}
+
+		return marshalResponse(responses)
+	}
+
+	resp := s.handle(ctx, msg)
+	if resp == nil {
+		return nil
+	}
+
+	return marshalResponse(resp)
+}
+
+func (s *Server) handle(ctx context.Context, msg []byte) *response {
+	var req request
+
+	err := json.Unmarshal(msg, &req)
+	if err != nil {
+		return errorResponse(nil, &rpcError{Code: codeParseError, Message: "parse error: " + err.Error()})
+	}
+
+	if req.Method == "" && len(req.ID) > 0 && req.JSONRPC == "2.0" {
+		// The server does not send requests, so there are no responses to handle.
+		return nil
+	}
+
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, &rpcError{Code: codeInvalidRequest, Message: "invalid JSON-RPC 2.0 request"})
+	}
+
+	result, err := s.call(ctx, req.Method, req.Params)
+
+	if len(req.ID) == 0 {
+		// Notifications must not be answered.
+		if err != nil {
+			slog.Warn(fmt.Sprintf("mcp notification %s: %s", req.Method, err))
+		}
+
+		return nil
+	}
+
+	if err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			slog.Error(fmt.Sprintf("mcp %s: %s", req.Method, err))
+			rpcErr = &rpcError{Code: codeInternalError, Message: err.Error()}
+		}
+
+		return errorResponse(req.ID, rpcErr)
+	}
+
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+func (s *Server) call(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		return s.initialize(params)
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return s.listTools(), nil
+	case "tools/call":
+		return s.callTool(ctx, params)
+	case "notifications/initialized", "notifications/cancelled":
+		return nil, nil
+	default:
+		return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", method)}
+	}
+}
+
+func (s *Server) initialize(params json.RawMessage) (any, error) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+
+	err := unmarshalParams(params, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	version := p.ProtocolVersion
+	if !slices.Contains(supportedProtocolVersions, version) {
+		version = LatestProtocolVersion
+	}
+
+	return map[string]any{
+		"protocolVersion": version,
+		"capabilities": map[string]any{
+			"tools": map[string]any{"listChanged": false},
+		},
+		"serverInfo": map[string]any{
+			"name":    s.Name,
+			"version": s.Version,
+		},
+	}, nil
+}
+
+func (s *Server) listTools() any {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return map[string]any{"tools": s.tools}
+}
+
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (any, error) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+
+	err := unmarshalParams(params, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	handler := s.toolHandler(p.Name)
+	if handler == nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool %q", p.Name)}
+	}
+
+	if len(p.Arguments) == 0 || string(p.Arguments) == "null" {
+		p.Arguments = json.RawMessage("{}")
+	}
+
+	result, err := handler(ctx, p.Arguments)
+	if err != nil {
+		slog.Error(fmt.Sprintf("mcp tool %s: %s", p.Name, err))
+		return ErrorResult(err), nil
+	}
+
+	return result, nil
+}
+
+func (s *Server) toolHandler(name string) func(context.Context, json.RawMessage) (*ToolResult, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	i := slices.IndexFunc(s.tools, func(t Tool) bool { return t.Name == name })
+	if i < 0 {
+		return nil
+	}
+
+	return s.tools[i].Handler
+}
+
+func unmarshalParams(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		return nil
+	}
+
+	err := json.Unmarshal(params, v)
+	if err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+
+	return nil
+}
+
+func errorResponse(id json.RawMessage, err *rpcError) *response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+
+	return &response{JSONRPC: "2.0", ID: id, Error: err}
+}
+
+func marshalResponse(v any) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("marshal mcp response: " + err.Error())
+		return nil
+	}
+
+	return b
+}
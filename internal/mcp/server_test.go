@@ -0,0 +1,122 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer() *Server {
+	s := &Server{Name: "test", Version: "1.0"}
+	s.AddTool(Tool{
+		Name:        "echo",
+		Description: "Echoes the text",
+		InputSchema: map[string]any{"type": "object"},
+		Handler: func(_ context.Context, args json.RawMessage) (*ToolResult, error) {
+			var a struct {
+				Text string `json:"text"`
+			}
+
+			err := json.Unmarshal(args, &a)
+			if err != nil {
+				return nil, err
+			}
+
+			if a.Text == "" {
+				return nil, errors.New("empty text")
+			}
+
+			return TextResult(a.Text), nil
+		},
+	})
+
+	return s
+}
+
+func TestServerHandleMessage(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		message string
+		expect  string
+	}{
+		{
+			name:    "initialize",
+			message: `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"c","version":"1"}}}`,
+			expect:  `{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2025-03-26","capabilities":{"tools":{"listChanged":false}},"serverInfo":{"name":"test","version":"1.0"}}}`,
+		},
+		{
+			name:    "initialize with unsupported version",
+			message: `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2000-01-01"}}`,
+			expect:  `{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2025-06-18","capabilities":{"tools":{"listChanged":false}},"serverInfo":{"name":"test","version":"1.0"}}}`,
+		},
+		{
+			name:    "notification",
+			message: `{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		},
+		{
+			name:    "ping",
+			message: `{"jsonrpc":"2.0","id":"a","method":"ping"}`,
+			expect:  `{"jsonrpc":"2.0","id":"a","result":{}}`,
+		},
+		{
+			name:    "list tools",
+			message: `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+			expect:  `{"jsonrpc":"2.0","id":2,"result":{"tools":[{"name":"echo","description":"Echoes the text","inputSchema":{"type":"object"}}]}}`,
+		},
+		{
+			name:    "call tool",
+			message: `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hello"}}}`,
+			expect:  `{"jsonrpc":"2.0","id":3,"result":{"content":[{"type":"text","text":"hello"}]}}`,
+		},
+		{
+			name:    "tool error",
+			message: `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{}}}`,
+			expect:  `{"jsonrpc":"2.0","id":3,"result":{"content":[{"type":"text","text":"empty text"}],"isError":true}}`,
+		},
+		{
+			name:    "unknown tool",
+			message: `{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"unknown"}}`,
+			expect:  `{"jsonrpc":"2.0","id":4,"error":{"code":-32602,"message":"unknown tool \"unknown\""}}`,
+		},
+		{
+			name:    "unknown method",
+			message: `{"jsonrpc":"2.0","id":5,"method":"resources/list"}`,
+			expect:  `{"jsonrpc":"2.0","id":5,"error":{"code":-32601,"message":"method \"resources/list\" not found"}}`,
+		},
+		{
+			name:    "parse error",
+			message: `{"jsonrpc":`,
+			expect:  `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error: unexpected end of JSON input"}}`,
+		},
+		{
+			name:    "batch",
+			message: `[{"jsonrpc":"2.0","id":1,"method":"ping"},{"jsonrpc":"2.0","method":"notifications/initialized"}]`,
+			expect:  `[{"jsonrpc":"2.0","id":1,"result":{}}]`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := newTestServer().HandleMessage(context.Background(), []byte(tc.message))
+
+			if tc.expect == "" {
+				require.Nil(t, resp)
+				return
+			}
+
+			require.JSONEq(t, tc.expect, string(resp))
+		})
+	}
+}
+
+func TestServerServeStdio(t *testing.T) {
+	in := strings.NewReader(`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n\n" + `{"jsonrpc":"2.0","id":1,"method":"ping"}` + "\n")
+	var out bytes.Buffer
+
+	err := newTestServer().ServeStdio(context.Background(), in, &out)
+	require.NoError(t, err)
+	require.Equal(t, `{"jsonrpc":"2.0","id":1,"result":{}}`+"\n", out.String())
+}
@@ -0,0 +1,167 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mgoltzsche/knowledgebot/internal/qna"
+)
+
+// maxSearchLimit is the maximum number of chunks the search tool returns.
+const maxSearchLimit = 50
+
+// AddKnowledgeTools registers the tools that query the knowledge base using the given workflow.
+func AddKnowledgeTools(s *Server, w *qna.QuestionAnswerWorkflow) {
+	s.AddTool(Tool{
+		Name:        "search_knowledge",
+		Description: fmt.Sprintf("Searches the knowledge base about %q and returns the most relevant text snippets along with their source URLs and relevance scores.", w.Topic),
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"query": map[string]any{
+					"type":        "string",
+					"description": "The search query, e.g. a question or keywords",
+				},
+				"limit": map[string]any{
+					"type":        "integer",
+					"description": "Maximum number of snippets to return",
+					"minimum":     1,
+					"maximum":     maxSearchLimit,
+				},
+			},
+			"required": []string{"query"},
+		},
+		Handler: searchKnowledgeHandler(w),
+	})
+	s.AddTool(Tool{
+		Name:        "ask_knowledgebot",
+		Description: fmt.Sprintf("Answers a question about %q using the knowledge base and returns the answer along with the sources it is based on.", w.Topic),
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"question": map[string]any{
+					"type":        "string",
+					"description": "The question to answer",
+				},
+			},
+			"required": []string{"question"},
+		},
+		Handler: askKnowledgeBotHandler(w),
+	})
+}
+
+func searchKnowledgeHandler(w *qna.QuestionAnswerWorkflow) func(context.Context, json.RawMessage) (*ToolResult, error) {
+	return func(ctx context.Context, args json.RawMessage) (*ToolResult, error) {
+		var a struct {
+			Query string `json:"query"`
+			Limit int    `json:"limit"`
+		}
+
+		err := json.Unmarshal(args, &a)
+		if err != nil {
+			return ErrorResult(fmt.Errorf("invalid arguments: %w", err)), nil
+		}
+
+		if strings.TrimSpace(a.Query) == "" {
+			return ErrorResult(errors.New("query must not be empty")), nil
+		}
+
+		if a.Limit <= 0 {
+			a.Limit = w.MaxDocs
+		}
+
+		resp, err := w.Search(ctx, qna.SearchRequest{
+			Query:          a.Query,
+			Limit:          min(a.Limit, maxSearchLimit),
+			ScoreThreshold: w.ScoreThreshold,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(resp.Sources) == 0 {
+			return TextResult("No relevant snippets found."), nil
+		}
+
+		var text strings.Builder
+
+		for i, src := range resp.Sources {
+			_, _ = fmt.Fprintf(&text, "[%d] %s\nURL: %s\n", i+1, src.Title, src.URL)
+
+			for _, snippet := range src.Snippets {
+				_, _ = fmt.Fprintf(&text, "\nScore: %.3f\n%s\n", snippet.Score, snippet.Text)
+			}
+
+			text.WriteString("\n")
+		}
+
+		result := TextResult(strings.TrimSpace(text.String()))
+		result.StructuredContent = resp
+
+		return result, nil
+	}
+}
+
+func askKnowledgeBotHandler(w *qna.QuestionAnswerWorkflow) func(context.Context, json.RawMessage) (*ToolResult, error) {
+	return func(ctx context.Context, args json.RawMessage) (*ToolResult, error) {
+		var a struct {
+			Question string `json:"question"`
+		}
+
+		err := json.Unmarshal(args, &a)
+		if err != nil {
+			return ErrorResult(fmt.Errorf("invalid arguments: %w", err)), nil
+		}
+
+		if strings.TrimSpace(a.Question) == "" {
+			return ErrorResult(errors.New("question must not be empty")), nil
+		}
+
+		ch, err := w.Answer(ctx, qna.Request{Question: a.Question})
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			answer  strings.Builder
+			sources []qna.SourceReference
+		)
+
+		for chunk := range ch {
+			if chunk.Err != nil {
+				for range ch {
+				}
+
+				return nil, chunk.Err
+			}
+
+			answer.WriteString(chunk.Chunk)
+
+			if chunk.Sources != nil {
+				sources = chunk.Sources
+			}
+		}
+
+		text := answer.String()
+
+		if len(sources) > 0 {
+			refs := make([]string, len(sources))
+			for i, src := range sources {
+				refs[i] = fmt.Sprintf("[%d] %s: %s", i+1, src.Title, src.URL)
+			}
+
+			text += "\n\nSources:\n" + strings.Join(refs, "\n")
+		}
+
+		result := TextResult(text)
+		result.StructuredContent = map[string]any{
+			"answer":  answer.String(),
+			"sources": sources,
+		}
+
+		return result, nil
+	}
+}
@@ -0,0 +1,151 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// maxMessageSize is the maximum size of a message received by the server.
+const maxMessageSize = 4 << 20
+
+// ServeStdio reads newline-delimited JSON-RPC messages from r and writes the responses to w until r is closed or the context is canceled.
+// Requests are handled concurrently so that e.g. a ping can be answered while a tool call is running.
+func (s *Server) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+	)
+
+	defer wg.Wait()
+
+	lines := make(chan []byte)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(lines)
+
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+
+		for scanner.Scan() {
+			line := bytes.Clone(scanner.Bytes())
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		errCh <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				err := <-errCh
+				if err != nil {
+					return fmt.Errorf("read mcp message: %w", err)
+				}
+
+				return nil
+			}
+
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				resp := s.HandleMessage(ctx, line)
+				if resp == nil {
+					return
+				}
+
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				_, err := w.Write(append(resp, '\n'))
+				if err != nil {
+					slog.Error("write mcp response: " + err.Error())
+				}
+			}()
+		}
+	}
+}
+
+// HTTPHandler returns a handler that implements the streamable HTTP transport.
+// Since the server does not send requests or notifications to the client, it always responds with a JSON body
+// and does not support server-sent event streams.
+func (s *Server) HTTPHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !isAllowedOrigin(req) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxMessageSize))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, "message too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			http.Error(w, "read request: "+err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		resp := s.HandleMessage(req.Context(), body)
+		if resp == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(resp)
+	})
+}
+
+// isAllowedOrigin prevents DNS rebinding attacks by rejecting browser requests from other origins than the server's.
+func isAllowedOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	if u.Host == req.Host {
+		return true
+	}
+
+	ip := net.ParseIP(u.Hostname())
+
+	return u.Hostname() == "localhost" || (ip != nil && ip.IsLoopback())
+}
@@ -0,0 +1,57 @@
+package qdrantutils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// GetAliasTarget returns the name of the collection the given alias points to or an empty string if there is no such alias.
+func GetAliasTarget(ctx context.Context, qdrantURL, alias string) (string, error) {
+	var result struct {
+		Aliases []struct {
+			AliasName      string `json:"alias_name"`
+			CollectionName string `json:"collection_name"`
+		} `json:"aliases"`
+	}
+
+	err := doRequest(ctx, http.MethodGet, qdrantURL+"/aliases", nil, &result)
+	if err != nil {
+		return "", fmt.Errorf("list qdrant aliases: %w", err)
+	}
+
+	for _, a := range result.Aliases {
+		if a.AliasName == alias {
+			return a.CollectionName, nil
+		}
+	}
+
+	return "", nil
+}
+
+// SetAlias points the alias to the given collection, replacing an existing alias with the same name atomically.
+func SetAlias(ctx context.Context, qdrantURL, alias, collection string) error {
+	target, err := GetAliasTarget(ctx, qdrantURL, alias)
+	if err != nil {
+		return err
+	}
+
+	actions := make([]map[string]any, 0, 2)
+
+	if target != "" {
+		actions = append(actions, map[string]any{
+			"delete_alias": map[string]any{"alias_name": alias},
+		})
+	}
+
+	actions = append(actions, map[string]any{
+		"create_alias": map[string]any{"alias_name": alias, "collection_name": collection},
+	})
+
+	err = doRequest(ctx, http.MethodPost, qdrantURL+"/collections/aliases", map[string]any{"actions": actions}, nil)
+	if err != nil {
+		return fmt.Errorf("set qdrant alias %s to collection %s: %w", alias, collection, err)
+	}
+
+	return nil
+}
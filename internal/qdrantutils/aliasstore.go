@@ -0,0 +1,158 @@
+package qdrantutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// AliasStore is a store that follows a Qdrant alias.
+// It resolves the alias on every request and uses the collection it points to along with the embedding model of that collection.
+// This allows to switch the alias to a collection that was embedded using another model without restarting the server
+// while the query embedding model and the searched collection never disagree.
+type AliasStore struct {
+	qdrantURL    string
+	alias        string
+	defaultModel string
+	newStore     func(collection, embeddingModel string) (*Store, error)
+	mutex        sync.Mutex
+	store        *Store
+	collection   string
+}
+
+var _ vectorstores.VectorStore = &AliasStore{}
+
+// NewAliasStore creates a store for the given alias.
+// The defaultModel is used for collections that do not contain embedding model information.
+// The newStore function is called to create a store for a collection and its embedding model.
+func NewAliasStore(ctx context.Context, qdrantURL, alias, defaultModel string, newStore func(collection, embeddingModel string) (*Store, error)) (*AliasStore, error) {
+	s := &AliasStore{
+		qdrantURL:    qdrantURL,
+		alias:        alias,
+		defaultModel: defaultModel,
+		newStore:     newStore,
+	}
+
+	target, err := GetAliasTarget(ctx, qdrantURL, alias)
+	if err != nil {
+		return nil, err
+	}
+
+	if target == "" {
+		return nil, fmt.Errorf("qdrant alias %s does not exist", alias)
+	}
+
+	err = s.switchTo(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// EmbeddingModel returns the embedding model of the collection the alias currently points to.
+func (s *AliasStore) EmbeddingModel() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.store.EmbeddingModel
+}
+
+// current returns the store for the collection the alias currently points to.
+// When the alias cannot be resolved, the previously resolved collection is used.
+func (s *AliasStore) current(ctx context.Context) (*Store, error) {
+	target, err := GetAliasTarget(ctx, s.qdrantURL, s.alias)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err != nil || target == "" {
+		if err == nil {
+			err = errors.New("alias does not exist")
+		}
+
+		slog.Warn(fmt.Sprintf("failed to resolve qdrant alias %s, using collection %s: %s", s.alias, s.collection, err))
+
+		return s.store, nil
+	}
+
+	if target != s.collection {
+		err = s.switchTo(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.store, nil
+}
+
+// switchTo replaces the store with one for the given collection and its embedding model.
+func (s *AliasStore) switchTo(ctx context.Context, collection string) error {
+	model, err := GetEmbeddingModel(ctx, s.qdrantURL, collection)
+	if err != nil {
+		return err
+	}
+
+	if model == "" {
+		model = s.defaultModel
+	}
+
+	store, err := s.newStore(collection, model)
+	if err != nil {
+		return err
+	}
+
+	if s.collection != "" {
+		slog.Info(fmt.Sprintf("qdrant alias %s points to collection %s of embedding model %s now, switching from collection %s of embedding model %s", s.alias, collection, model, s.collection, s.store.EmbeddingModel))
+	}
+
+	s.store = store
+	s.collection = collection
+
+	return nil
+}
+
+// AddDocuments adds the given documents to the collection the alias points to using its embedding model.
+func (s *AliasStore) AddDocuments(ctx context.Context, docs []schema.Document, options ...vectorstores.Option) ([]string, error) {
+	store, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.AddDocuments(ctx, docs, options...)
+}
+
+// SimilaritySearch embeds the query using the embedding model of the collection the alias points to and returns the most similar documents.
+func (s *AliasStore) SimilaritySearch(ctx context.Context, query string, numDocuments int, options ...vectorstores.Option) ([]schema.Document, error) {
+	store, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.SimilaritySearch(ctx, query, numDocuments, options...)
+}
+
+// KeywordSearch returns the documents that match the keywords of the given query best.
+func (s *AliasStore) KeywordSearch(ctx context.Context, query string, numDocuments int, options ...vectorstores.Option) ([]schema.Document, error) {
+	store, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.KeywordSearch(ctx, query, numDocuments, options...)
+}
+
+// DeleteDocumentsByURL deletes all document chunks that were indexed for the given URL.
+func (s *AliasStore) DeleteDocumentsByURL(ctx context.Context, u string) error {
+	store, err := s.current(ctx)
+	if err != nil {
+		return err
+	}
+
+	return store.DeleteDocumentsByURL(ctx, u)
+}
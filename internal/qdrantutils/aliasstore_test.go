@@ -0,0 +1,52 @@
+package qdrantutils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAliasStoreFollowsAlias(t *testing.T) {
+	target := "live-v1"
+	models := map[string]string{"live-v1": "all-minilm", "live-v2": "nomic-embed-text"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/aliases":
+			_, _ = w.Write([]byte(`{"result":{"aliases":[{"alias_name":"live","collection_name":"` + target + `"}]},"status":"ok"}`))
+		case "/collections/live-v1/points/scroll", "/collections/live-v2/points/scroll":
+			collection := req.URL.Path[len("/collections/") : len(req.URL.Path)-len("/points/scroll")]
+			_, _ = w.Write([]byte(`{"result":{"points":[{"id":"a","payload":{"embedding_model":"` + models[collection] + `"}}]},"status":"ok"}`))
+		default:
+			t.Errorf("unexpected request %s", req.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	var created []string
+
+	newStore := func(collection, embeddingModel string) (*Store, error) {
+		created = append(created, collection+"/"+embeddingModel)
+		return &Store{EmbeddingModel: embeddingModel, collection: collection}, nil
+	}
+
+	ctx := context.Background()
+
+	s, err := NewAliasStore(ctx, srv.URL, "live", "all-minilm", newStore)
+	require.NoError(t, err)
+	require.Equal(t, "all-minilm", s.EmbeddingModel(), "initial model")
+
+	store, err := s.current(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "live-v1", store.collection, "initial collection")
+
+	target = "live-v2"
+
+	store, err = s.current(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "live-v2", store.collection, "switched collection")
+	require.Equal(t, "nomic-embed-text", store.EmbeddingModel, "switched model")
+	require.Equal(t, []string{"live-v1/all-minilm", "live-v2/nomic-embed-text"}, created)
+}
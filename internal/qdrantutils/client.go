@@ -0,0 +1,89 @@
+package qdrantutils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+var httpClient = &http.Client{Timeout: 60 * time.Second}
+
+// statusError is returned when the Qdrant API responds with an unexpected status code.
+type statusError struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("server responded with %s: %s", e.Status, e.Message)
+}
+
+// IsNotFound returns true if the error was caused by a 404 response, e.g. because the collection does not exist.
+func IsNotFound(err error) bool {
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
+
+type response struct {
+	Result json.RawMessage `json:"result"`
+	Status any             `json:"status"`
+}
+
+// doRequest sends a request to the Qdrant API and unmarshals the response's result into the given result value.
+func doRequest(ctx context.Context, method, url string, body, result any) error {
+	var reqBody io.Reader
+
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshal request body: %w", err)
+		}
+
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &statusError{StatusCode: resp.StatusCode, Status: resp.Status, Message: string(bytes.TrimSpace(msg))}
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	var r response
+
+	err = json.NewDecoder(resp.Body).Decode(&r)
+	if err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	err = json.Unmarshal(r.Result, result)
+	if err != nil {
+		return fmt.Errorf("decode response result: %w", err)
+	}
+
+	return nil
+}
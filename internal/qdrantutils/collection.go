@@ -6,24 +6,40 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
 	"net/url"
+	"slices"
 	"time"
 )
 
-func CreateQdrantCollectionIfNotExist(ctx context.Context, qdrantURL, collection string, dimensions int) error {
-	body, err := json.Marshal(map[string]any{
+// EmbeddingModelKey is the payload key the name of the embedding model that produced a point's vector is stored within.
+const EmbeddingModelKey = "embedding_model"
+
+// CreateQdrantCollectionIfNotExist creates the collection unless it exists.
+// When keywords is true, the collection is created with a sparse vector for keyword search
+// and an existing collection is checked to have one.
+func CreateQdrantCollectionIfNotExist(ctx context.Context, qdrantURL, collection string, dimensions int, keywords bool) error {
+	config := map[string]any{
 		"vectors": map[string]any{
 			"size":     dimensions,
 			"distance": "Cosine",
 			"datatype": "float16",
 		},
-	})
+	}
+
+	if keywords {
+		config["sparse_vectors"] = map[string]any{
+			KeywordsVectorName: map[string]any{"modifier": "idf"},
+		}
+	}
+
+	body, err := json.Marshal(config)
 	if err != nil {
 		return fmt.Errorf("create qdrant collection: marshal request body: %w", err)
 	}
 
-	collectionURL := fmt.Sprintf("%s/collections/%s", qdrantURL, url.PathEscape(collection))
+	collectionURL := collectionURL(qdrantURL, collection)
 	httpClient := &http.Client{Timeout: 30 * time.Second}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPut, collectionURL, bytes.NewReader(body))
@@ -36,14 +52,267 @@ func CreateQdrantCollectionIfNotExist(ctx context.Context, qdrantURL, collection
 		return fmt.Errorf("create qdrant collection: %w", err)
 	}
 
+	defer resp.Body.Close()
+
 	if resp.StatusCode == http.StatusOK {
 		slog.Info("created qdrant collection " + collection)
-		return nil
+		return createPayloadIndexes(ctx, collectionURL)
 	}
 
 	if resp.StatusCode == http.StatusConflict {
-		return nil
+		if keywords {
+			err = checkSparseVector(ctx, collectionURL, collection, KeywordsVectorName)
+			if err != nil {
+				return err
+			}
+		}
+
+		return createPayloadIndexes(ctx, collectionURL)
 	}
 
 	return fmt.Errorf("create qdrant collection: server responded with %s", resp.Status)
 }
+
+func createPayloadIndexes(ctx context.Context, collectionURL string) error {
+	for _, field := range []string{"url", EmbeddingModelKey} {
+		err := createPayloadIndex(ctx, collectionURL, field)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createPayloadIndex creates a keyword index for the given payload field.
+// It does not fail when the index exists already.
+func createPayloadIndex(ctx context.Context, collectionURL, field string) error {
+	body := map[string]any{
+		"field_name":   field,
+		"field_schema": "keyword",
+	}
+
+	err := doRequest(ctx, http.MethodPut, collectionURL+"/index?wait=true", body, nil)
+	if err != nil {
+		return fmt.Errorf("create qdrant payload index for field %q: %w", field, err)
+	}
+
+	return nil
+}
+
+// checkSparseVector returns an error if the collection does not have a sparse vector with the given name.
+func checkSparseVector(ctx context.Context, collectionURL, collection, name string) error {
+	var info struct {
+		Config struct {
+			Params struct {
+				SparseVectors map[string]any `json:"sparse_vectors"`
+			} `json:"params"`
+		} `json:"config"`
+	}
+
+	err := doRequest(ctx, http.MethodGet, collectionURL, nil, &info)
+	if err != nil {
+		return fmt.Errorf("get qdrant collection info: %w", err)
+	}
+
+	if _, ok := info.Config.Params.SparseVectors[name]; !ok {
+		return fmt.Errorf("qdrant collection %q was created without the sparse vector %q that is required for keyword search, please recreate it", collection, name)
+	}
+
+	return nil
+}
+
+// CollectionInfo describes a Qdrant collection.
+type CollectionInfo struct {
+	Name                string
+	Status              string
+	PointsCount         int
+	IndexedVectorsCount int
+	// Vectors holds the dense vector configurations by name; the default vector's name is empty.
+	Vectors map[string]VectorParams
+	// SparseVectors holds the names of the sparse vectors.
+	SparseVectors []string
+	// PayloadIndexes maps the indexed payload fields to their index type.
+	PayloadIndexes map[string]string
+}
+
+// VectorParams is the configuration of a dense vector.
+type VectorParams struct {
+	Size     int    `json:"size"`
+	Distance string `json:"distance"`
+	Datatype string `json:"datatype"`
+}
+
+// ListCollections returns the names of the collections, sorted alphabetically.
+func ListCollections(ctx context.Context, qdrantURL string) ([]string, error) {
+	var result struct {
+		Collections []struct {
+			Name string `json:"name"`
+		} `json:"collections"`
+	}
+
+	err := doRequest(ctx, http.MethodGet, qdrantURL+"/collections", nil, &result)
+	if err != nil {
+		return nil, fmt.Errorf("list qdrant collections: %w", err)
+	}
+
+	names := make([]string, len(result.Collections))
+	for i, c := range result.Collections {
+		names[i] = c.Name
+	}
+
+	slices.Sort(names)
+
+	return names, nil
+}
+
+// GetCollectionInfo returns the configuration and size of the given collection.
+func GetCollectionInfo(ctx context.Context, qdrantURL, collection string) (*CollectionInfo, error) {
+	var result struct {
+		Status              string `json:"status"`
+		PointsCount         int    `json:"points_count"`
+		IndexedVectorsCount int    `json:"indexed_vectors_count"`
+		Config              struct {
+			Params struct {
+				Vectors       json.RawMessage `json:"vectors"`
+				SparseVectors map[string]any  `json:"sparse_vectors"`
+			} `json:"params"`
+		} `json:"config"`
+		PayloadSchema map[string]struct {
+			DataType string `json:"data_type"`
+		} `json:"payload_schema"`
+	}
+
+	err := doRequest(ctx, http.MethodGet, collectionURL(qdrantURL, collection), nil, &result)
+	if err != nil {
+		return nil, fmt.Errorf("get qdrant collection info: %w", err)
+	}
+
+	info := &CollectionInfo{
+		Name:                collection,
+		Status:              result.Status,
+		PointsCount:         result.PointsCount,
+		IndexedVectorsCount: result.IndexedVectorsCount,
+		SparseVectors:       slices.Sorted(maps.Keys(result.Config.Params.SparseVectors)),
+		PayloadIndexes:      make(map[string]string, len(result.PayloadSchema)),
+	}
+
+	for field, schema := range result.PayloadSchema {
+		info.PayloadIndexes[field] = schema.DataType
+	}
+
+	info.Vectors, err = parseVectorParams(result.Config.Params.Vectors)
+	if err != nil {
+		return nil, fmt.Errorf("get qdrant collection info: %w", err)
+	}
+
+	return info, nil
+}
+
+// parseVectorParams parses the vector configuration which is either a single unnamed vector or a map of named vectors.
+func parseVectorParams(raw json.RawMessage) (map[string]VectorParams, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return map[string]VectorParams{}, nil
+	}
+
+	var unnamed VectorParams
+
+	err := json.Unmarshal(raw, &unnamed)
+	if err == nil && unnamed.Size > 0 {
+		return map[string]VectorParams{"": unnamed}, nil
+	}
+
+	var named map[string]VectorParams
+
+	err = json.Unmarshal(raw, &named)
+	if err != nil {
+		return nil, fmt.Errorf("parse vector config: %w", err)
+	}
+
+	return named, nil
+}
+
+// DeleteCollection deletes the given collection along with all of its points.
+func DeleteCollection(ctx context.Context, qdrantURL, collection string) error {
+	var deleted bool
+
+	err := doRequest(ctx, http.MethodDelete, collectionURL(qdrantURL, collection), nil, &deleted)
+	if err != nil {
+		return fmt.Errorf("delete qdrant collection: %w", err)
+	}
+
+	if !deleted {
+		return fmt.Errorf("delete qdrant collection: collection %q does not exist", collection)
+	}
+
+	return nil
+}
+
+// FindOtherEmbeddingModel returns the name of an embedding model other than the given one that produced vectors within the collection.
+// It returns an empty string if all points that specify an embedding model were embedded using the given one.
+func FindOtherEmbeddingModel(ctx context.Context, qdrantURL, collection, embeddingModel string) (string, error) {
+	body := map[string]any{
+		"filter": map[string]any{
+			"must_not": []map[string]any{
+				{"is_empty": map[string]any{"key": EmbeddingModelKey}},
+				{"key": EmbeddingModelKey, "match": map[string]any{"value": embeddingModel}},
+			},
+		},
+		"limit":        1,
+		"with_payload": []string{EmbeddingModelKey},
+		"with_vector":  false,
+	}
+
+	var result struct {
+		Points []ScoredPoint `json:"points"`
+	}
+
+	err := doRequest(ctx, http.MethodPost, collectionURL(qdrantURL, collection)+"/points/scroll", body, &result)
+	if err != nil {
+		return "", fmt.Errorf("find embedding models of qdrant collection: %w", err)
+	}
+
+	for _, p := range result.Points {
+		if model, _ := p.Payload[EmbeddingModelKey].(string); model != "" {
+			return model, nil
+		}
+	}
+
+	return "", nil
+}
+
+// GetEmbeddingModel returns the name of the embedding model that produced the vectors of a point within the collection.
+// It returns an empty string if the collection does not contain any point that specifies an embedding model.
+func GetEmbeddingModel(ctx context.Context, qdrantURL, collection string) (string, error) {
+	body := map[string]any{
+		"filter": map[string]any{
+			"must_not": []map[string]any{
+				{"is_empty": map[string]any{"key": EmbeddingModelKey}},
+			},
+		},
+		"limit":        1,
+		"with_payload": []string{EmbeddingModelKey},
+		"with_vector":  false,
+	}
+
+	var result struct {
+		Points []ScoredPoint `json:"points"`
+	}
+
+	err := doRequest(ctx, http.MethodPost, collectionURL(qdrantURL, collection)+"/points/scroll", body, &result)
+	if err != nil {
+		return "", fmt.Errorf("get embedding model of qdrant collection: %w", err)
+	}
+
+	for _, p := range result.Points {
+		if model, _ := p.Payload[EmbeddingModelKey].(string); model != "" {
+			return model, nil
+		}
+	}
+
+	return "", nil
+}
+
+func collectionURL(qdrantURL, collection string) string {
+	return fmt.Sprintf("%s/collections/%s", qdrantURL, url.PathEscape(collection))
+}
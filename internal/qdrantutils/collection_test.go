@@ -0,0 +1,95 @@
+package qdrantutils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCollectionInfo(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		vectors       string
+		expectVectors map[string]VectorParams
+	}{
+		{
+			name:          "unnamed vector",
+			vectors:       `{"size":384,"distance":"Cosine","datatype":"float16"}`,
+			expectVectors: map[string]VectorParams{"": {Size: 384, Distance: "Cosine", Datatype: "float16"}},
+		},
+		{
+			name:          "named vectors",
+			vectors:       `{"text":{"size":768,"distance":"Dot"}}`,
+			expectVectors: map[string]VectorParams{"text": {Size: 768, Distance: "Dot"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				require.Equal(t, "/collections/my%20docs", req.URL.EscapedPath())
+				_, _ = w.Write([]byte(`{"result":{
+					"status":"green","points_count":42,"indexed_vectors_count":40,
+					"config":{"params":{"vectors":` + tc.vectors + `,"sparse_vectors":{"keywords":{"modifier":"idf"}}}},
+					"payload_schema":{"url":{"data_type":"keyword","points":42}}
+				},"status":"ok"}`))
+			}))
+			defer srv.Close()
+
+			info, err := GetCollectionInfo(context.Background(), srv.URL, "my docs")
+			require.NoError(t, err)
+			require.Equal(t, &CollectionInfo{
+				Name:                "my docs",
+				Status:              "green",
+				PointsCount:         42,
+				IndexedVectorsCount: 40,
+				Vectors:             tc.expectVectors,
+				SparseVectors:       []string{"keywords"},
+				PayloadIndexes:      map[string]string{"url": "keyword"},
+			}, info)
+		})
+	}
+}
+
+func TestFindOtherEmbeddingModel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/collections/missing/points/scroll" {
+			http.Error(w, `{"status":{"error":"Not found"}}`, http.StatusNotFound)
+			return
+		}
+
+		var body struct {
+			Filter struct {
+				MustNot []map[string]any `json:"must_not"`
+			} `json:"filter"`
+		}
+
+		err := json.NewDecoder(req.Body).Decode(&body)
+		require.NoError(t, err)
+		require.Len(t, body.Filter.MustNot, 2)
+
+		model := body.Filter.MustNot[1]["match"].(map[string]any)["value"]
+		if model == "all-minilm" {
+			_, _ = w.Write([]byte(`{"result":{"points":[]},"status":"ok"}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"result":{"points":[{"id":"a","payload":{"embedding_model":"all-minilm"}}]},"status":"ok"}`))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+
+	model, err := FindOtherEmbeddingModel(ctx, srv.URL, "docs", "all-minilm")
+	require.NoError(t, err)
+	require.Empty(t, model, "same model")
+
+	model, err = FindOtherEmbeddingModel(ctx, srv.URL, "docs", "nomic-embed-text")
+	require.NoError(t, err)
+	require.Equal(t, "all-minilm", model, "other model")
+
+	_, err = FindOtherEmbeddingModel(ctx, srv.URL, "missing", "all-minilm")
+	require.True(t, IsNotFound(err), "IsNotFound(%v)", err)
+}
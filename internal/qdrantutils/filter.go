@@ -0,0 +1,48 @@
+package qdrantutils
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// MatchFilter converts the given metadata values into a Qdrant filter that matches the points whose payload contains all of them.
+func MatchFilter(values map[string]any) map[string]any {
+	conditions := make([]map[string]any, 0, len(values))
+
+	for key, value := range values {
+		if f, ok := value.(float64); ok {
+			if f != math.Trunc(f) {
+				// Qdrant matches integers, strings and booleans only.
+				conditions = append(conditions, map[string]any{"key": key, "range": map[string]any{"gte": f, "lte": f}})
+				continue
+			}
+
+			value = int64(f)
+		}
+
+		conditions = append(conditions, map[string]any{"key": key, "match": map[string]any{"value": value}})
+	}
+
+	slices.SortFunc(conditions, func(a, b map[string]any) int {
+		return cmp.Compare(a["key"].(string), b["key"].(string))
+	})
+
+	return map[string]any{"must": conditions}
+}
+
+// withMatchFilter converts a filter that is specified as map of metadata values into a Qdrant filter.
+func withMatchFilter(options []vectorstores.Option) []vectorstores.Option {
+	opts := vectorstores.Options{}
+	for _, o := range options {
+		o(&opts)
+	}
+
+	if values, ok := opts.Filters.(map[string]any); ok && len(values) > 0 {
+		return append(slices.Clone(options), vectorstores.WithFilters(MatchFilter(values)))
+	}
+
+	return options
+}
@@ -0,0 +1,26 @@
+package qdrantutils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchFilter(t *testing.T) {
+	filter := MatchFilter(map[string]any{
+		"url":     "https://example.org",
+		"page":    float64(3),
+		"score":   0.5,
+		"indexed": true,
+	})
+
+	b, err := json.Marshal(filter)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"must":[
+		{"key":"indexed","match":{"value":true}},
+		{"key":"page","match":{"value":3}},
+		{"key":"score","range":{"gte":0.5,"lte":0.5}},
+		{"key":"url","match":{"value":"https://example.org"}}
+	]}`, string(b))
+}
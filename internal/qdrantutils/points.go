@@ -0,0 +1,326 @@
+package qdrantutils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/schema"
+)
+
+// pointIDNamespace is the UUID namespace point IDs are derived within.
+var pointIDNamespace = uuid.MustParse("0d90e28c-ee71-4db8-9649-810b506ffd04")
+
+// Point is a Qdrant point.
+type Point struct {
+	ID string
+	// Vector is the point's dense vector.
+	Vector []float32
+	// SparseVectors maps the names of the point's sparse vectors to their values.
+	SparseVectors map[string]SparseVector
+	Payload       map[string]any
+}
+
+func (p Point) MarshalJSON() ([]byte, error) {
+	var vector any
+
+	if len(p.SparseVectors) > 0 {
+		// The unnamed dense vector is referred to by an empty name.
+		vectors := make(map[string]any, len(p.SparseVectors)+1)
+		if p.Vector != nil {
+			vectors[""] = p.Vector
+		}
+
+		for name, v := range p.SparseVectors {
+			vectors[name] = v
+		}
+
+		vector = vectors
+	} else if p.Vector != nil {
+		vector = p.Vector
+	}
+
+	return json.Marshal(struct {
+		ID      string         `json:"id"`
+		Vector  any            `json:"vector,omitempty"`
+		Payload map[string]any `json:"payload,omitempty"`
+	}{p.ID, vector, p.Payload})
+}
+
+// ScoredPoint is a Qdrant search result.
+type ScoredPoint struct {
+	ID      string         `json:"id"`
+	Score   float32        `json:"score"`
+	Payload map[string]any `json:"payload"`
+	// Vector is either the unnamed dense vector or, when the collection has sparse vectors, a map of named vectors.
+	Vector json.RawMessage `json:"vector,omitempty"`
+}
+
+// DenseVector returns the point's unnamed dense vector.
+func (p *ScoredPoint) DenseVector() ([]float32, error) {
+	raw := p.Vector
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	if raw[0] == '{' {
+		var vectors map[string]json.RawMessage
+
+		err := json.Unmarshal(raw, &vectors)
+		if err != nil {
+			return nil, fmt.Errorf("parse vectors of point %s: %w", p.ID, err)
+		}
+
+		var ok bool
+
+		raw, ok = vectors[""]
+		if !ok {
+			return nil, nil
+		}
+	}
+
+	var vector []float32
+
+	err := json.Unmarshal(raw, &vector)
+	if err != nil {
+		return nil, fmt.Errorf("parse vector of point %s: %w", p.ID, err)
+	}
+
+	return vector, nil
+}
+
+// HasVector returns true if the point contains the named vector.
+func (p *ScoredPoint) HasVector(name string) bool {
+	var vectors map[string]json.RawMessage
+
+	if json.Unmarshal(p.Vector, &vectors) != nil {
+		return false
+	}
+
+	_, ok := vectors[name]
+
+	return ok
+}
+
+// PointID derives a stable point ID from the document's URL and content.
+// The chunk position is deliberately not part of the ID so that inserting a paragraph into a page does not change the IDs of the chunks that follow it.
+func PointID(doc schema.Document) string {
+	u, _ := doc.Metadata["url"].(string)
+	h := sha256.Sum256([]byte(doc.PageContent))
+	name := append([]byte(u+"\n"), h[:]...)
+
+	return uuid.NewSHA1(pointIDNamespace, name).String()
+}
+
+// GetPoints returns the given points that exist within the collection along with their payload and the given named vectors, mapped by point ID.
+func GetPoints(ctx context.Context, qdrantURL, collection string, ids []string, vectorNames []string) (map[string]ScoredPoint, error) {
+	u := fmt.Sprintf("%s/collections/%s/points", qdrantURL, url.PathEscape(collection))
+	body := map[string]any{
+		"ids":          ids,
+		"with_payload": true,
+		"with_vector":  len(vectorNames) > 0,
+	}
+
+	if len(vectorNames) > 0 {
+		body["with_vector"] = vectorNames
+	}
+
+	var points []ScoredPoint
+
+	err := doRequest(ctx, http.MethodPost, u, body, &points)
+	if err != nil {
+		return nil, fmt.Errorf("get qdrant points: %w", err)
+	}
+
+	existing := make(map[string]ScoredPoint, len(points))
+	for _, p := range points {
+		existing[p.ID] = p
+	}
+
+	return existing, nil
+}
+
+// UpdateVectors sets the vectors of the given existing points, keeping their other vectors and payload.
+func UpdateVectors(ctx context.Context, qdrantURL, collection string, points []Point) error {
+	u := fmt.Sprintf("%s/collections/%s/points/vectors?wait=true", qdrantURL, url.PathEscape(collection))
+	body := map[string]any{
+		"points": points,
+	}
+
+	err := doRequest(ctx, http.MethodPut, u, body, nil)
+	if err != nil {
+		return fmt.Errorf("update qdrant point vectors: %w", err)
+	}
+
+	return nil
+}
+
+// OverwritePayloads replaces the payloads of the given existing points, keeping their vectors.
+func OverwritePayloads(ctx context.Context, qdrantURL, collection string, points []Point) error {
+	u := fmt.Sprintf("%s/collections/%s/points/batch?wait=true", qdrantURL, url.PathEscape(collection))
+	operations := make([]map[string]any, len(points))
+
+	for i, p := range points {
+		operations[i] = map[string]any{
+			"overwrite_payload": map[string]any{
+				"payload": p.Payload,
+				"points":  []string{p.ID},
+			},
+		}
+	}
+
+	err := doRequest(ctx, http.MethodPost, u, map[string]any{"operations": operations}, nil)
+	if err != nil {
+		return fmt.Errorf("overwrite qdrant point payloads: %w", err)
+	}
+
+	return nil
+}
+
+// UpsertPoints inserts the given points into the collection or replaces the existing points with the same ID.
+func UpsertPoints(ctx context.Context, qdrantURL, collection string, points []Point) error {
+	u := fmt.Sprintf("%s/collections/%s/points?wait=true", qdrantURL, url.PathEscape(collection))
+	body := map[string]any{
+		"points": points,
+	}
+
+	err := doRequest(ctx, http.MethodPut, u, body, nil)
+	if err != nil {
+		return fmt.Errorf("upsert qdrant points: %w", err)
+	}
+
+	return nil
+}
+
+// DeletePointsByPayload deletes all points of a collection whose payload key matches the given value.
+func DeletePointsByPayload(ctx context.Context, qdrantURL, collection, key string, value any) error {
+	return DeletePointsByPayloadExcept(ctx, qdrantURL, collection, key, value, nil)
+}
+
+// DeletePointsByPayloadExcept deletes the points of a collection whose payload key matches the given value, except the points with the given IDs.
+func DeletePointsByPayloadExcept(ctx context.Context, qdrantURL, collection, key string, value any, keepIDs []string) error {
+	u := fmt.Sprintf("%s/collections/%s/points/delete?wait=true", qdrantURL, url.PathEscape(collection))
+	filter := matchFilter(key, value)
+
+	if len(keepIDs) > 0 {
+		filter["must_not"] = []map[string]any{{"has_id": keepIDs}}
+	}
+
+	body := map[string]any{
+		"filter": filter,
+	}
+
+	err := doRequest(ctx, http.MethodPost, u, body, nil)
+	if err != nil {
+		return fmt.Errorf("delete qdrant points where %s=%v: %w", key, value, err)
+	}
+
+	return nil
+}
+
+func matchFilter(key string, value any) map[string]any {
+	return map[string]any{
+		"must": []map[string]any{
+			{"key": key, "match": map[string]any{"value": value}},
+		},
+	}
+}
+
+// ScrollDocuments calls fn with batches of the documents stored within the collection until all documents were passed or fn returns an error.
+// The documents' metadata contains the embedding model (see EmbeddingModelKey).
+// When withVectors is true, the documents' dense vectors are passed to fn as well.
+func ScrollDocuments(ctx context.Context, qdrantURL, collection string, batchSize int, withVectors bool, fn func(docs []schema.Document, vectors [][]float32) error) error {
+	u := fmt.Sprintf("%s/collections/%s/points/scroll", qdrantURL, url.PathEscape(collection))
+
+	var offset any
+
+	for {
+		body := map[string]any{
+			"limit":        batchSize,
+			"with_payload": true,
+			"with_vector":  withVectors,
+		}
+
+		if offset != nil {
+			body["offset"] = offset
+		}
+
+		var result struct {
+			Points         []ScoredPoint `json:"points"`
+			NextPageOffset any           `json:"next_page_offset"`
+		}
+
+		err := doRequest(ctx, http.MethodPost, u, body, &result)
+		if err != nil {
+			return fmt.Errorf("scroll qdrant points: %w", err)
+		}
+
+		docs := make([]schema.Document, 0, len(result.Points))
+
+		var vectors [][]float32
+
+		for _, p := range result.Points {
+			content, ok := p.Payload[contentKey].(string)
+			if !ok {
+				return fmt.Errorf("payload of point %s does not contain content key %q", p.ID, contentKey)
+			}
+
+			delete(p.Payload, contentKey)
+
+			docs = append(docs, schema.Document{PageContent: content, Metadata: p.Payload})
+
+			if withVectors {
+				vector, err := p.DenseVector()
+				if err != nil {
+					return err
+				}
+
+				vectors = append(vectors, vector)
+			}
+		}
+
+		if len(docs) > 0 {
+			err = fn(docs, vectors)
+			if err != nil {
+				return err
+			}
+		}
+
+		if result.NextPageOffset == nil {
+			return nil
+		}
+
+		offset = result.NextPageOffset
+	}
+}
+
+// QuerySparse returns the points whose given sparse vector matches the query vector best.
+// The filter is optional.
+func QuerySparse(ctx context.Context, qdrantURL, collection, vectorName string, query SparseVector, limit int, filter any) ([]ScoredPoint, error) {
+	u := fmt.Sprintf("%s/collections/%s/points/query", qdrantURL, url.PathEscape(collection))
+	body := map[string]any{
+		"query":        query,
+		"using":        vectorName,
+		"limit":        limit,
+		"with_payload": true,
+	}
+
+	if filter != nil {
+		body["filter"] = filter
+	}
+
+	var result struct {
+		Points []ScoredPoint `json:"points"`
+	}
+
+	err := doRequest(ctx, http.MethodPost, u, body, &result)
+	if err != nil {
+		return nil, fmt.Errorf("query qdrant points: %w", err)
+	}
+
+	return result.Points, nil
+}
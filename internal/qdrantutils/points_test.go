@@ -0,0 +1,98 @@
+package qdrantutils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+)
+
+func TestPointID(t *testing.T) {
+	doc := func(url, content string) schema.Document {
+		return schema.Document{
+			PageContent: content,
+			Metadata:    map[string]any{"url": url, "title": "ignored"},
+		}
+	}
+
+	id := PointID(doc("https://example.org/a", "some chunk"))
+
+	require.Equal(t, id, PointID(doc("https://example.org/a", "some chunk")), "same url and content")
+	require.NotEqual(t, id, PointID(doc("https://example.org/b", "some chunk")), "different url")
+	require.NotEqual(t, id, PointID(doc("https://example.org/a", "other chunk")), "different content")
+	require.Len(t, id, 36, "uuid")
+}
+
+func TestPointMarshalJSON(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		point  Point
+		expect string
+	}{
+		{
+			name:   "dense vector",
+			point:  Point{ID: "a", Vector: []float32{0.5, 1}, Payload: map[string]any{"url": "u"}},
+			expect: `{"id":"a","vector":[0.5,1],"payload":{"url":"u"}}`,
+		},
+		{
+			name: "dense and sparse vector",
+			point: Point{ID: "a", Vector: []float32{0.5}, SparseVectors: map[string]SparseVector{
+				KeywordsVectorName: {Indices: []uint32{3}, Values: []float32{0.7}},
+			}},
+			expect: `{"id":"a","vector":{"":[0.5],"keywords":{"indices":[3],"values":[0.7]}}}`,
+		},
+		{
+			name:   "without vector",
+			point:  Point{ID: "a"},
+			expect: `{"id":"a"}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := json.Marshal(tc.point)
+			require.NoError(t, err)
+			require.JSONEq(t, tc.expect, string(b))
+		})
+	}
+}
+
+func TestScrollDocuments(t *testing.T) {
+	pages := map[string]string{
+		"":  `{"result":{"points":[{"id":"a","payload":{"content":"chunk a","url":"https://example.org/a"},"vector":[0.5,1]}],"next_page_offset":"b"},"status":"ok"}`,
+		"b": `{"result":{"points":[{"id":"b","payload":{"content":"chunk b","url":"https://example.org/b"},"vector":{"":[1,0],"keywords":{"indices":[1],"values":[1]}}}],"next_page_offset":null},"status":"ok"}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body struct {
+			Offset string `json:"offset"`
+			Limit  int    `json:"limit"`
+		}
+
+		err := json.NewDecoder(req.Body).Decode(&body)
+		require.NoError(t, err)
+		require.Equal(t, 1, body.Limit)
+
+		_, _ = w.Write([]byte(pages[body.Offset]))
+	}))
+	defer srv.Close()
+
+	var (
+		docs    []schema.Document
+		vectors [][]float32
+	)
+
+	err := ScrollDocuments(context.Background(), srv.URL, "docs", 1, true, func(batchDocs []schema.Document, batchVectors [][]float32) error {
+		docs = append(docs, batchDocs...)
+		vectors = append(vectors, batchVectors...)
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []schema.Document{
+		{PageContent: "chunk a", Metadata: map[string]any{"url": "https://example.org/a"}},
+		{PageContent: "chunk b", Metadata: map[string]any{"url": "https://example.org/b"}},
+	}, docs)
+	require.Equal(t, [][]float32{{0.5, 1}, {1, 0}}, vectors)
+}
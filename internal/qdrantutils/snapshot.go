@@ -0,0 +1,129 @@
+package qdrantutils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// snapshotClient transfers snapshots without a timeout since they can be large; the context cancels transfers.
+var snapshotClient = &http.Client{}
+
+// Snapshot describes a collection snapshot stored on the Qdrant server.
+type Snapshot struct {
+	Name         string `json:"name"`
+	CreationTime string `json:"creation_time"`
+	Size         int64  `json:"size"`
+}
+
+// CreateSnapshot creates a snapshot of the given collection on the Qdrant server.
+func CreateSnapshot(ctx context.Context, qdrantURL, collection string) (*Snapshot, error) {
+	var snapshot Snapshot
+
+	err := doRequest(ctx, http.MethodPost, collectionURL(qdrantURL, collection)+"/snapshots?wait=true", nil, &snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("create qdrant snapshot: %w", err)
+	}
+
+	return &snapshot, nil
+}
+
+// DownloadSnapshot writes the given snapshot of the collection to w.
+func DownloadSnapshot(ctx context.Context, qdrantURL, collection, snapshot string, w io.Writer) error {
+	u := fmt.Sprintf("%s/snapshots/%s", collectionURL(qdrantURL, collection), url.PathEscape(snapshot))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("download qdrant snapshot: %w", err)
+	}
+
+	resp, err := snapshotClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("download qdrant snapshot: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download qdrant snapshot: server responded with %s", resp.Status)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	if err != nil {
+		return fmt.Errorf("download qdrant snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// RecoverSnapshot restores the collection from the snapshot at the given location, replacing the collection's data.
+// The location must be a URL the Qdrant server can access, e.g. an http(s) URL or a file:// URL pointing to a file on the server.
+func RecoverSnapshot(ctx context.Context, qdrantURL, collection, location string) error {
+	body := map[string]any{
+		"location": location,
+		"priority": "snapshot",
+	}
+
+	err := doRequest(ctx, http.MethodPut, collectionURL(qdrantURL, collection)+"/snapshots/recover?wait=true", body, nil)
+	if err != nil {
+		return fmt.Errorf("recover qdrant snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// UploadSnapshot restores the collection from the given local snapshot file, replacing the collection's data.
+func UploadSnapshot(ctx context.Context, qdrantURL, collection, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("upload qdrant snapshot: %w", err)
+	}
+
+	defer f.Close()
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+
+	go func() {
+		part, err := mw.CreateFormFile("snapshot", filepath.Base(file))
+		if err == nil {
+			_, err = io.Copy(part, f)
+		}
+
+		if err == nil {
+			err = mw.Close()
+		}
+
+		pw.CloseWithError(err)
+	}()
+
+	u := collectionURL(qdrantURL, collection) + "/snapshots/upload?wait=true&priority=snapshot"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, pr)
+	if err != nil {
+		_ = pr.CloseWithError(err)
+		return fmt.Errorf("upload qdrant snapshot: %w", err)
+	}
+
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	resp, err := snapshotClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("upload qdrant snapshot: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("upload qdrant snapshot: server responded with %s: %s", resp.Status, string(bytes.TrimSpace(msg)))
+	}
+
+	return nil
+}
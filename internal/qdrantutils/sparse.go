@@ -0,0 +1,112 @@
+package qdrantutils
+
+import (
+	"hash/fnv"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// KeywordsVectorName is the name of the sparse vector that holds the keyword weights of a point.
+const KeywordsVectorName = "keywords"
+
+const (
+	// bm25K1 controls the term frequency saturation.
+	bm25K1 = 1.2
+	// bm25B controls the document length normalization.
+	bm25B = 0.75
+	// bm25AvgDocLength is the assumed average number of keywords per chunk.
+	// Since documents are indexed incrementally, the actual average is unknown at indexing time.
+	bm25AvgDocLength = 100
+)
+
+// stopWords are frequent English words that are not indexed as keywords.
+var stopWords = map[string]struct{}{}
+
+func init() {
+	for _, w := range strings.Fields(`a an and are as at be but by for from has have he her his how i if in into is it its
+		me my not of on or she so that the their them then there these they this to was we were what when where which
+		who whom why will with you your`) {
+		stopWords[w] = struct{}{}
+	}
+}
+
+// SparseVector is a Qdrant sparse vector.
+type SparseVector struct {
+	Indices []uint32  `json:"indices"`
+	Values  []float32 `json:"values"`
+}
+
+// KeywordVector returns the BM25 term frequency weights of the keywords within the given document text.
+// The inverse document frequency is applied by Qdrant at query time (idf modifier).
+func KeywordVector(text string) SparseVector {
+	tokens := keywordTokens(text)
+	termFreqs := map[uint32]int{}
+
+	for _, t := range tokens {
+		termFreqs[keywordIndex(t)]++
+	}
+
+	lengthNorm := 1 - bm25B + bm25B*float64(len(tokens))/bm25AvgDocLength
+
+	return sparseVector(termFreqs, func(tf int) float32 {
+		return float32(float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*lengthNorm))
+	})
+}
+
+// KeywordQueryVector returns a sparse vector that weights each keyword within the given query equally.
+func KeywordQueryVector(query string) SparseVector {
+	termFreqs := map[uint32]int{}
+
+	for _, t := range keywordTokens(query) {
+		termFreqs[keywordIndex(t)] = 1
+	}
+
+	return sparseVector(termFreqs, func(int) float32 { return 1 })
+}
+
+func sparseVector(termFreqs map[uint32]int, weight func(tf int) float32) SparseVector {
+	v := SparseVector{
+		Indices: make([]uint32, 0, len(termFreqs)),
+		Values:  make([]float32, 0, len(termFreqs)),
+	}
+
+	for idx := range termFreqs {
+		v.Indices = append(v.Indices, idx)
+	}
+
+	slices.Sort(v.Indices)
+
+	for _, idx := range v.Indices {
+		v.Values = append(v.Values, weight(termFreqs[idx]))
+	}
+
+	return v
+}
+
+// keywordTokens splits the given text into lower case keywords.
+// Underscores are kept within keywords so that identifiers such as ERR_NOT_FOUND remain intact.
+func keywordTokens(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	tokens := words[:0]
+
+	for _, w := range words {
+		w = strings.Trim(w, "_")
+		if _, stopWord := stopWords[w]; stopWord || len(w) < 2 && !strings.ContainsFunc(w, unicode.IsDigit) {
+			continue
+		}
+
+		tokens = append(tokens, w)
+	}
+
+	return tokens
+}
+
+func keywordIndex(token string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(token))
+
+	return h.Sum32()
+}
@@ -0,0 +1,39 @@
+package qdrantutils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeywordTokens(t *testing.T) {
+	for _, tc := range []struct {
+		input  string
+		expect []string
+	}{
+		{"", []string{}},
+		{"Who is the captain of the Planet Express ship?", []string{"captain", "planet", "express", "ship"}},
+		{"Episode S01E02 fails with ERR_NOT_FOUND (code 7)", []string{"episode", "s01e02", "fails", "err_not_found", "code", "7"}},
+		{"all-minilm v1.2", []string{"all", "minilm", "v1", "2"}},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			require.Equal(t, tc.expect, keywordTokens(tc.input))
+		})
+	}
+}
+
+func TestKeywordVector(t *testing.T) {
+	v := KeywordVector("Bender Bender Fry")
+	require.Len(t, v.Indices, 2)
+	require.Len(t, v.Values, 2)
+
+	weights := map[uint32]float32{}
+	for i, idx := range v.Indices {
+		weights[idx] = v.Values[i]
+	}
+
+	require.Greater(t, weights[keywordIndex("bender")], weights[keywordIndex("fry")], "repeated term weight")
+
+	q := KeywordQueryVector("Bender and bender")
+	require.Equal(t, SparseVector{Indices: []uint32{keywordIndex("bender")}, Values: []float32{1}}, q)
+}
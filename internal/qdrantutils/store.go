@@ -0,0 +1,256 @@
+package qdrantutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/mgoltzsche/knowledgebot/internal/importer"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+	"github.com/tmc/langchaingo/vectorstores/qdrant"
+)
+
+const contentKey = "content"
+
+// Store is a Qdrant vector store that, in addition to the langchaingo implementation, supports deleting documents.
+// Documents are stored using deterministic point IDs (see PointID), making repeated imports of the same document idempotent.
+type Store struct {
+	qdrant.Store
+	// EmbeddingModel is stored within the payload of added points in order to detect when the collection is used with another model.
+	EmbeddingModel string
+	embedder       embeddings.Embedder
+	url            string
+	collection     string
+	keywords       bool
+}
+
+var _ vectorstores.VectorStore = &Store{}
+
+// NewStore creates a Qdrant store.
+// When keywords is true, the keywords of added documents are indexed as a sparse vector in order to support KeywordSearch.
+func NewStore(qdrantURL, collection string, embedder embeddings.Embedder, keywords bool) (*Store, error) {
+	u, err := url.Parse(qdrantURL)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := qdrant.New(
+		qdrant.WithURL(*u),
+		qdrant.WithCollectionName(collection),
+		qdrant.WithEmbedder(embedder),
+		qdrant.WithContentKey(contentKey),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		Store:      store,
+		embedder:   embedder,
+		url:        qdrantURL,
+		collection: collection,
+		keywords:   keywords,
+	}, nil
+}
+
+// AddDocuments upserts the given documents.
+// Documents that exist within the collection already are not embedded again but their payload is updated when their metadata changed.
+func (s *Store) AddDocuments(ctx context.Context, docs []schema.Document, _ ...vectorstores.Option) ([]string, error) {
+	return s.addDocuments(ctx, docs, nil)
+}
+
+// AddDocumentsWithVectors upserts the given documents along with their precomputed vectors instead of embedding them.
+// The vectors must have been produced by the store's embedding model.
+func (s *Store) AddDocumentsWithVectors(ctx context.Context, docs []schema.Document, vectors [][]float32) ([]string, error) {
+	if len(vectors) != len(docs) {
+		return nil, errors.New("number of vectors does not match number of documents")
+	}
+
+	return s.addDocuments(ctx, docs, vectors)
+}
+
+// addDocuments upserts the documents that do not exist within the collection, embedding them unless vectors are provided.
+// The payload of existing documents is overwritten when it differs.
+// When keywords are enabled, existing documents without a keyword vector, e.g. written without hybrid search, are given one.
+func (s *Store) addDocuments(ctx context.Context, docs []schema.Document, vectors [][]float32) ([]string, error) {
+	ids := make([]string, len(docs))
+	newDocs := make(map[string]int, len(docs))
+	newIDs := make([]string, 0, len(docs))
+
+	for i, doc := range docs {
+		id := PointID(doc)
+		ids[i] = id
+
+		if _, ok := newDocs[id]; !ok {
+			newDocs[id] = i
+			newIDs = append(newIDs, id)
+		}
+	}
+
+	var vectorNames []string
+	if s.keywords {
+		vectorNames = []string{KeywordsVectorName}
+	}
+
+	existing, err := GetPoints(ctx, s.url, s.collection, newIDs, vectorNames)
+	if err != nil {
+		return nil, err
+	}
+
+	texts := make([]string, 0, len(newIDs))
+	points := make([]Point, 0, len(newIDs))
+
+	var changed, missingKeywords []Point
+
+	for _, id := range newIDs {
+		i := newDocs[id]
+		doc := docs[i]
+		payload := s.payload(doc)
+		point := Point{ID: id, Payload: payload}
+
+		if p, ok := existing[id]; ok {
+			if !importer.EqualMetadata(p.Payload, payload) {
+				changed = append(changed, point)
+			}
+
+			if s.keywords && !p.HasVector(KeywordsVectorName) {
+				missingKeywords = append(missingKeywords, Point{
+					ID:            id,
+					SparseVectors: map[string]SparseVector{KeywordsVectorName: KeywordVector(doc.PageContent)},
+				})
+			}
+
+			continue
+		}
+
+		if vectors != nil {
+			point.Vector = vectors[i]
+		}
+
+		texts = append(texts, doc.PageContent)
+		points = append(points, point)
+	}
+
+	if len(changed) > 0 {
+		err = OverwritePayloads(ctx, s.url, s.collection, changed)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(missingKeywords) > 0 {
+		err = UpdateVectors(ctx, s.url, s.collection, missingKeywords)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(points) == 0 {
+		return ids, nil
+	}
+
+	if vectors == nil {
+		embedded, err := s.embedder.EmbedDocuments(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(embedded) != len(points) {
+			return nil, errors.New("number of vectors from embedder does not match number of documents")
+		}
+
+		for i, v := range embedded {
+			points[i].Vector = v
+		}
+	}
+
+	if s.keywords {
+		for i := range points {
+			points[i].SparseVectors = map[string]SparseVector{KeywordsVectorName: KeywordVector(texts[i])}
+		}
+	}
+
+	err = UpsertPoints(ctx, s.url, s.collection, points)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// payload returns the payload of the point the given document is stored as.
+func (s *Store) payload(doc schema.Document) map[string]any {
+	payload := make(map[string]any, len(doc.Metadata)+2)
+
+	for k, v := range doc.Metadata {
+		payload[k] = v
+	}
+
+	payload[contentKey] = doc.PageContent
+
+	if s.EmbeddingModel != "" {
+		payload[EmbeddingModelKey] = s.EmbeddingModel
+	}
+
+	return payload
+}
+
+// DeleteDocumentsByURL deletes all document chunks that were indexed for the given URL.
+func (s *Store) DeleteDocumentsByURL(ctx context.Context, u string) error {
+	return DeletePointsByPayload(ctx, s.url, s.collection, "url", u)
+}
+
+// DeleteDocumentsByURLExcept deletes the document chunks that were indexed for the given URL, except the chunks with the given IDs.
+func (s *Store) DeleteDocumentsByURLExcept(ctx context.Context, u string, keepIDs []string) error {
+	return DeletePointsByPayloadExcept(ctx, s.url, s.collection, "url", u, keepIDs)
+}
+
+// SimilaritySearch returns the documents that are most similar to the given query.
+// A filter can be specified as map of metadata values that the documents must match (see MatchFilter).
+func (s *Store) SimilaritySearch(ctx context.Context, query string, numDocuments int, options ...vectorstores.Option) ([]schema.Document, error) {
+	return s.Store.SimilaritySearch(ctx, query, numDocuments, withMatchFilter(options)...)
+}
+
+// KeywordSearch returns the documents that match the keywords of the given query best, BM25-style.
+func (s *Store) KeywordSearch(ctx context.Context, query string, numDocuments int, options ...vectorstores.Option) ([]schema.Document, error) {
+	if !s.keywords {
+		return nil, errors.New("keyword search is not enabled")
+	}
+
+	vector := KeywordQueryVector(query)
+	if len(vector.Indices) == 0 {
+		return nil, nil
+	}
+
+	opts := vectorstores.Options{}
+	for _, o := range withMatchFilter(options) {
+		o(&opts)
+	}
+
+	points, err := QuerySparse(ctx, s.url, s.collection, KeywordsVectorName, vector, numDocuments, opts.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]schema.Document, 0, len(points))
+
+	for _, p := range points {
+		content, ok := p.Payload[contentKey].(string)
+		if !ok {
+			return nil, fmt.Errorf("payload of point %s does not contain content key %q", p.ID, contentKey)
+		}
+
+		delete(p.Payload, contentKey)
+
+		docs = append(docs, schema.Document{
+			PageContent: content,
+			Metadata:    p.Payload,
+			Score:       p.Score,
+		})
+	}
+
+	return docs, nil
+}
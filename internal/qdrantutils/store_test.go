@@ -0,0 +1,112 @@
+package qdrantutils
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+)
+
+type failingEmbedder struct{}
+
+func (failingEmbedder) EmbedDocuments(context.Context, []string) ([][]float32, error) {
+	panic("existing documents must not be embedded")
+}
+
+func (failingEmbedder) EmbedQuery(context.Context, string) ([]float32, error) {
+	panic("existing documents must not be embedded")
+}
+
+func TestStoreUpdatesPayloadOfExistingDocuments(t *testing.T) {
+	unchanged := schema.Document{PageContent: "unchanged", Metadata: map[string]any{"url": "https://example.org/a", "title": "Title", "page": 1}}
+	changed := schema.Document{PageContent: "changed", Metadata: map[string]any{"url": "https://example.org/a", "title": "New title"}}
+
+	var overwritten string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/collections/docs/points":
+			_, _ = w.Write([]byte(`{"result":[
+				{"id":"` + PointID(unchanged) + `","payload":{"url":"https://example.org/a","title":"Title","page":1,"content":"unchanged"}},
+				{"id":"` + PointID(changed) + `","payload":{"url":"https://example.org/a","title":"Old title","section":"Old","content":"changed"}}
+			],"status":"ok"}`))
+		case "/collections/docs/points/batch":
+			b, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+
+			overwritten = string(b)
+
+			_, _ = w.Write([]byte(`{"result":[],"status":"ok"}`))
+		default:
+			t.Errorf("unexpected request %s", req.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	store, err := NewStore(srv.URL, "docs", failingEmbedder{}, false)
+	require.NoError(t, err)
+
+	ids, err := store.AddDocuments(context.Background(), []schema.Document{unchanged, changed})
+	require.NoError(t, err)
+	require.Equal(t, []string{PointID(unchanged), PointID(changed)}, ids)
+
+	expected, err := json.Marshal(map[string]any{"operations": []any{map[string]any{
+		"overwrite_payload": map[string]any{
+			"payload": map[string]any{"url": "https://example.org/a", "title": "New title", "content": "changed"},
+			"points":  []string{PointID(changed)},
+		},
+	}}})
+	require.NoError(t, err)
+	require.JSONEq(t, string(expected), overwritten, "only the changed payload should be overwritten")
+}
+
+func TestStoreAddsKeywordVectorToExistingDocuments(t *testing.T) {
+	withKeywords := schema.Document{PageContent: "indexed with keywords", Metadata: map[string]any{"url": "https://example.org/a"}}
+	withoutKeywords := schema.Document{PageContent: "indexed without keywords", Metadata: map[string]any{"url": "https://example.org/a"}}
+
+	var updated string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/collections/docs/points":
+			b, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.Contains(t, string(b), `"with_vector":["keywords"]`)
+
+			_, _ = w.Write([]byte(`{"result":[
+				{"id":"` + PointID(withKeywords) + `","payload":{"url":"https://example.org/a","content":"indexed with keywords"},"vector":{"keywords":{"indices":[1],"values":[1]}}},
+				{"id":"` + PointID(withoutKeywords) + `","payload":{"url":"https://example.org/a","content":"indexed without keywords"},"vector":{}}
+			],"status":"ok"}`))
+		case "/collections/docs/points/vectors":
+			require.Equal(t, http.MethodPut, req.Method)
+
+			b, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+
+			updated = string(b)
+
+			_, _ = w.Write([]byte(`{"result":{},"status":"ok"}`))
+		default:
+			t.Errorf("unexpected request %s", req.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	store, err := NewStore(srv.URL, "docs", failingEmbedder{}, true)
+	require.NoError(t, err)
+
+	_, err = store.AddDocuments(context.Background(), []schema.Document{withKeywords, withoutKeywords})
+	require.NoError(t, err)
+
+	expected, err := json.Marshal(map[string]any{"points": []Point{{
+		ID:            PointID(withoutKeywords),
+		SparseVectors: map[string]SparseVector{KeywordsVectorName: KeywordVector(withoutKeywords.PageContent)},
+	}}})
+	require.NoError(t, err)
+	require.JSONEq(t, string(expected), updated, "only the point without keyword vector should be updated")
+}
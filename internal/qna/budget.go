@@ -0,0 +1,97 @@
+package qna
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/mgoltzsche/knowledgebot/internal/tokens"
+	"github.com/tmc/langchaingo/schema"
+)
+
+const (
+	// messageOverheadTokens approximates the tokens a chat template adds per message as well as per source within the prompt.
+	messageOverheadTokens = 4
+	// minTruncatedTokens is the minimum length of a truncated chunk in tokens.
+	// When less space is left within the context budget, the chunk is dropped instead.
+	minTruncatedTokens = 64
+)
+
+// contextFit holds the retrieved documents that fit into the context budget.
+type contextFit struct {
+	docs []schema.Document
+	// truncated holds the indices of the docs that were truncated to fit.
+	truncated map[int]bool
+	dropped   []schema.Document
+}
+
+// fitContext selects the documents that fit into the context budget left after reserving the given number of tokens.
+// The lowest-scored documents are dropped first, the last document that does not fit completely is truncated.
+func (w *QuestionAnswerWorkflow) fitContext(docs []schema.Document, reservedTokens int) contextFit {
+	if w.ContextTokens <= 0 {
+		return contextFit{docs: docs}
+	}
+
+	docs = slices.Clone(docs)
+	budget := w.ContextTokens - reservedTokens
+
+	order := make([]int, len(docs))
+	for i := range order {
+		order[i] = i
+	}
+
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(docs[b].Score, docs[a].Score)
+	})
+
+	keep := make([]bool, len(docs))
+	truncated := map[int]bool{}
+
+	for _, i := range order {
+		title, _ := docs[i].Metadata["title"].(string)
+		overhead := tokens.Estimate(title) + messageOverheadTokens
+		cost := tokens.Estimate(docs[i].PageContent) + overhead
+
+		if cost <= budget {
+			keep[i] = true
+			budget -= cost
+
+			continue
+		}
+
+		if budget-overhead >= minTruncatedTokens {
+			keep[i] = true
+			truncated[i] = true
+			docs[i].PageContent = tokens.Truncate(docs[i].PageContent, budget-overhead)
+		}
+
+		break
+	}
+
+	fit := contextFit{truncated: map[int]bool{}}
+
+	for i, doc := range docs {
+		if !keep[i] {
+			fit.dropped = append(fit.dropped, doc)
+			continue
+		}
+
+		if truncated[i] {
+			fit.truncated[len(fit.docs)] = true
+		}
+
+		fit.docs = append(fit.docs, doc)
+	}
+
+	return fit
+}
+
+// reservedTokens returns the number of tokens of the context window that are required for everything but the sources.
+func (w *QuestionAnswerWorkflow) reservedTokens(promptTokens int, question string, history []Message) int {
+	reserved := w.AnswerTokens + promptTokens + tokens.Estimate(question) + 2*messageOverheadTokens
+
+	for _, m := range w.recentHistory(history) {
+		reserved += tokens.Estimate(m.Content) + messageOverheadTokens
+	}
+
+	return reserved
+}
@@ -0,0 +1,85 @@
+package qna
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+)
+
+func TestFitContext(t *testing.T) {
+	// Each doc costs 100 content tokens plus 1 title token plus the overhead.
+	text := strings.Repeat("word ", 100)
+	docs := []schema.Document{
+		{PageContent: text, Score: 0.9, Metadata: map[string]any{"title": "A"}},
+		{PageContent: text, Score: 0.5, Metadata: map[string]any{"title": "C"}},
+		{PageContent: text, Score: 0.7, Metadata: map[string]any{"title": "B"}},
+	}
+	docCost := 100 + 1 + messageOverheadTokens
+
+	for _, tc := range []struct {
+		name            string
+		contextTokens   int
+		expectTitles    []string
+		expectTruncated map[int]bool
+		expectDropped   int
+	}{
+		{
+			name:          "disabled",
+			contextTokens: 0,
+			expectTitles:  []string{"A", "C", "B"},
+			expectDropped: 0,
+		},
+		{
+			name:            "all fit",
+			contextTokens:   3 * docCost,
+			expectTitles:    []string{"A", "C", "B"},
+			expectTruncated: map[int]bool{},
+		},
+		{
+			name:            "drop lowest-scored",
+			contextTokens:   2*docCost + 10,
+			expectTitles:    []string{"A", "B"},
+			expectTruncated: map[int]bool{},
+			expectDropped:   1,
+		},
+		{
+			name:            "truncate lowest-scored",
+			contextTokens:   2*docCost + 80,
+			expectTitles:    []string{"A", "C", "B"},
+			expectTruncated: map[int]bool{1: true},
+		},
+		{
+			name:            "drop all",
+			contextTokens:   10,
+			expectTruncated: map[int]bool{},
+			expectDropped:   3,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := &QuestionAnswerWorkflow{ContextTokens: tc.contextTokens}
+
+			fit := w.fitContext(docs, 0)
+
+			titles := make([]string, len(fit.docs))
+			for i, doc := range fit.docs {
+				titles[i] = doc.Metadata["title"].(string)
+			}
+
+			if len(tc.expectTitles) == 0 {
+				tc.expectTitles = []string{}
+			}
+
+			require.Equal(t, tc.expectTitles, titles, "docs")
+			require.Equal(t, tc.expectTruncated, fit.truncated, "truncated")
+			require.Len(t, fit.dropped, tc.expectDropped, "dropped")
+
+			for i := range fit.truncated {
+				require.Less(t, len(fit.docs[i].PageContent), len(text), "truncated text length")
+			}
+		})
+	}
+
+	require.Equal(t, text, docs[1].PageContent, "input docs must not be modified")
+}
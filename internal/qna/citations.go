@@ -0,0 +1,92 @@
+package qna
+
+import (
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxCitationLength is the maximum length of a citation marker such as "[1, 2]".
+// Longer bracketed text is not held back while waiting for the closing bracket.
+const maxCitationLength = 24
+
+var (
+	citationRegex        = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+	partialCitationRegex = regexp.MustCompile(`\[[\d,\s]*$`)
+)
+
+// Citation resolves a source number cited within the answer, e.g. [1], to the corresponding source.
+type Citation struct {
+	// Number is the cited source number, the 1-based index within the response's sources.
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+	Title  string `json:"title"`
+}
+
+// citationParser detects citation markers within the streamed answer.
+// Since a marker may be split across multiple stream chunks, text that may be the beginning of a marker is held back until it is complete.
+type citationParser struct {
+	sources []SourceReference
+	cited   map[int]bool
+	pending string
+}
+
+func newCitationParser(sources []SourceReference) *citationParser {
+	return &citationParser{sources: sources, cited: map[int]bool{}}
+}
+
+// Parse returns the response chunks for the given answer text chunk:
+// the text followed by a citation for each source that is cited within it for the first time.
+func (p *citationParser) Parse(text string) []ResponseChunk {
+	text = p.pending + text
+	p.pending = ""
+
+	if loc := partialCitationRegex.FindStringIndex(text); loc != nil && loc[1]-loc[0] < maxCitationLength {
+		p.pending = text[loc[0]:]
+		text = text[:loc[0]]
+	}
+
+	return p.chunks(text)
+}
+
+// Flush returns the response chunks for the text that has been held back.
+func (p *citationParser) Flush() []ResponseChunk {
+	text := p.pending
+	p.pending = ""
+
+	return p.chunks(text)
+}
+
+func (p *citationParser) chunks(text string) []ResponseChunk {
+	if text == "" {
+		return nil
+	}
+
+	chunks := []ResponseChunk{{Chunk: text}}
+
+	for _, m := range citationRegex.FindAllStringSubmatch(text, -1) {
+		for _, s := range strings.Split(m[1], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || p.cited[n] {
+				continue
+			}
+
+			p.cited[n] = true
+
+			if n < 1 || n > len(p.sources) {
+				slog.Warn("answer cites unknown source", "number", n, "sources", len(p.sources))
+				continue
+			}
+
+			src := p.sources[n-1]
+			chunks = append(chunks, ResponseChunk{Citation: &Citation{
+				Number: n,
+				URL:    src.URL,
+				Title:  src.Title,
+			}})
+		}
+	}
+
+	return chunks
+}
@@ -0,0 +1,75 @@
+package qna
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCitationParser(t *testing.T) {
+	sources := []SourceReference{
+		{URL: "https://example.org/fry", Title: "Fry"},
+		{URL: "https://example.org/leela", Title: "Leela"},
+	}
+	fry := &Citation{Number: 1, URL: "https://example.org/fry", Title: "Fry"}
+	leela := &Citation{Number: 2, URL: "https://example.org/leela", Title: "Leela"}
+
+	for _, tc := range []struct {
+		name   string
+		input  []string
+		expect []ResponseChunk
+	}{
+		{
+			name:   "citation within chunk",
+			input:  []string{"Fry is a delivery boy [1]."},
+			expect: []ResponseChunk{{Chunk: "Fry is a delivery boy [1]."}, {Citation: fry}},
+		},
+		{
+			name:  "citation split across chunks",
+			input: []string{"Fry is a delivery boy [", "1", "] and Leela", " the captain [2]."},
+			expect: []ResponseChunk{
+				{Chunk: "Fry is a delivery boy "},
+				{Chunk: "[1] and Leela"}, {Citation: fry},
+				{Chunk: " the captain [2]."}, {Citation: leela},
+			},
+		},
+		{
+			name:   "multiple sources within one citation",
+			input:  []string{"They work for Planet Express [2, 1]."},
+			expect: []ResponseChunk{{Chunk: "They work for Planet Express [2, 1]."}, {Citation: leela}, {Citation: fry}},
+		},
+		{
+			name:   "repeated citation",
+			input:  []string{"Fry [1] is Fry [1]"},
+			expect: []ResponseChunk{{Chunk: "Fry [1] is Fry [1]"}, {Citation: fry}},
+		},
+		{
+			name:   "unknown source",
+			input:  []string{"Bender [3]"},
+			expect: []ResponseChunk{{Chunk: "Bender [3]"}},
+		},
+		{
+			name:   "unterminated bracket at the end",
+			input:  []string{"Fry [", "1"},
+			expect: []ResponseChunk{{Chunk: "Fry "}, {Chunk: "[1"}},
+		},
+		{
+			name:   "bracketed text",
+			input:  []string{"Fry [the delivery boy]"},
+			expect: []ResponseChunk{{Chunk: "Fry [the delivery boy]"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newCitationParser(sources)
+
+			var chunks []ResponseChunk
+			for _, s := range tc.input {
+				chunks = append(chunks, p.Parse(s)...)
+			}
+
+			chunks = append(chunks, p.Flush()...)
+
+			require.Equal(t, tc.expect, chunks)
+		})
+	}
+}
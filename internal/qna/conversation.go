@@ -0,0 +1,100 @@
+package qna
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+
+	// maxTranscriptMessageLength is the maximum length of a message within the transcript used to rewrite a follow-up question.
+	maxTranscriptMessageLength = 1000
+
+	standaloneQuestionPrompt = `Given the following conversation and a follow-up question, rephrase the follow-up question to be a standalone question that can be understood without the conversation.
+Resolve references such as "he", "it" or "that episode" using the conversation.
+If the follow-up question is already standalone, return it unchanged.
+Respond with the standalone question only, without any explanation.`
+)
+
+// Message is a previous message of a conversation.
+type Message struct {
+	// Role is either "user" or "assistant".
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// historyMessages converts the most recent messages of the given conversation history into LLM messages.
+func (w *QuestionAnswerWorkflow) historyMessages(history []Message) ([]llms.MessageContent, error) {
+	history = w.recentHistory(history)
+	msgs := make([]llms.MessageContent, len(history))
+
+	for i, m := range history {
+		switch m.Role {
+		case RoleUser:
+			msgs[i] = llms.TextParts(llms.ChatMessageTypeHuman, m.Content)
+		case RoleAssistant:
+			// Citations refer to the sources of the previous answer and would be confused with the current ones.
+			msgs[i] = llms.TextParts(llms.ChatMessageTypeAI, citationRegex.ReplaceAllString(m.Content, ""))
+		default:
+			return nil, fmt.Errorf("unsupported message role %q", m.Role)
+		}
+	}
+
+	return msgs, nil
+}
+
+func (w *QuestionAnswerWorkflow) recentHistory(history []Message) []Message {
+	if w.MaxHistory >= 0 && len(history) > w.MaxHistory {
+		return history[len(history)-w.MaxHistory:]
+	}
+
+	return history
+}
+
+// standaloneQuestion rewrites a follow-up question into a question that can be used to search the knowledge base without the conversation history.
+func (w *QuestionAnswerWorkflow) standaloneQuestion(ctx context.Context, question string, history []Message) (string, error) {
+	history = w.recentHistory(history)
+	if len(history) == 0 {
+		return question, nil
+	}
+
+	var transcript strings.Builder
+
+	for _, m := range history {
+		content := m.Content
+		if r := []rune(content); len(r) > maxTranscriptMessageLength {
+			content = string(r[:maxTranscriptMessageLength]) + "..."
+		}
+
+		_, _ = fmt.Fprintf(&transcript, "%s: %s\n\n", m.Role, content)
+	}
+
+	_, _ = fmt.Fprintf(&transcript, "Follow-up question: %s", question)
+
+	resp, err := w.LLM.GenerateContent(ctx,
+		[]llms.MessageContent{
+			llms.TextParts(llms.ChatMessageTypeSystem, standaloneQuestionPrompt),
+			llms.TextParts(llms.ChatMessageTypeHuman, transcript.String()),
+		},
+		llms.WithTemperature(0),
+	)
+	if err != nil {
+		return "", fmt.Errorf("rewrite follow-up question: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return question, nil
+	}
+
+	rewritten := strings.Trim(strings.TrimSpace(resp.Choices[0].Content), `"`)
+	if rewritten == "" {
+		return question, nil
+	}
+
+	return rewritten, nil
+}
@@ -0,0 +1,98 @@
+package qna
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// rrfK dampens the influence of the top ranks within reciprocal rank fusion.
+const rrfK = 60
+
+// keywordOnlyShare is the inverse share of the fused candidates that may be keyword hits without a similar enough vector
+// when a score threshold is applied, since the threshold cannot be applied to them.
+const keywordOnlyShare = 4
+
+// KeywordSearcher is implemented by vector stores that support lexical keyword search.
+type KeywordSearcher interface {
+	KeywordSearch(ctx context.Context, query string, numDocuments int, options ...vectorstores.Option) ([]schema.Document, error)
+}
+
+// limitKeywordOnlyHits returns the keyword hits that are also contained within the given similarity search results
+// along with at most limit other keyword hits.
+func limitKeywordOnlyHits(keywordDocs, similarDocs []schema.Document, limit int) []schema.Document {
+	similar := make(map[string]struct{}, len(similarDocs))
+	for _, doc := range similarDocs {
+		similar[docKey(doc)] = struct{}{}
+	}
+
+	result := make([]schema.Document, 0, len(keywordDocs))
+
+	for _, doc := range keywordDocs {
+		if _, ok := similar[docKey(doc)]; !ok {
+			if limit <= 0 {
+				continue
+			}
+
+			limit--
+		}
+
+		result = append(result, doc)
+	}
+
+	return result
+}
+
+func docKey(doc schema.Document) string {
+	url, _ := doc.Metadata["url"].(string)
+	return url + "\n" + doc.PageContent
+}
+
+// fuseRankings merges the given result rankings using reciprocal rank fusion and returns the top documents.
+// The score of a fused document is normalized to the range [0, 1], 1 meaning that it ranked first within all rankings.
+func fuseRankings(limit int, rankings ...[]schema.Document) []schema.Document {
+	type fusedDoc struct {
+		doc   schema.Document
+		score float64
+		order int
+	}
+
+	fused := map[string]*fusedDoc{}
+
+	for _, ranking := range rankings {
+		for rank, doc := range ranking {
+			key := docKey(doc)
+
+			d, ok := fused[key]
+			if !ok {
+				d = &fusedDoc{doc: doc, order: len(fused)}
+				fused[key] = d
+			}
+
+			d.score += 1 / float64(rrfK+rank+1)
+		}
+	}
+
+	docs := make([]*fusedDoc, 0, len(fused))
+	for _, d := range fused {
+		docs = append(docs, d)
+	}
+
+	slices.SortFunc(docs, func(a, b *fusedDoc) int {
+		return cmp.Or(cmp.Compare(b.score, a.score), cmp.Compare(a.order, b.order))
+	})
+
+	maxScore := float64(len(rankings)) / (rrfK + 1)
+	result := make([]schema.Document, 0, min(limit, len(docs)))
+
+	for _, d := range docs[:min(limit, len(docs))] {
+		doc := d.doc
+		doc.Score = float32(d.score / maxScore)
+		result = append(result, doc)
+	}
+
+	return result
+}
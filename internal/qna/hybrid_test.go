@@ -0,0 +1,46 @@
+package qna
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+)
+
+func TestFuseRankings(t *testing.T) {
+	doc := func(content string) schema.Document {
+		return schema.Document{PageContent: content, Metadata: map[string]any{"url": "https://example.org"}, Score: 0.5}
+	}
+
+	dense := []schema.Document{doc("a"), doc("b"), doc("c")}
+	keywords := []schema.Document{doc("c"), doc("d"), doc("a")}
+
+	fused := fuseRankings(3, dense, keywords)
+
+	contents := make([]string, len(fused))
+	for i, d := range fused {
+		contents[i] = d.PageContent
+	}
+
+	require.Equal(t, []string{"a", "c", "b"}, contents)
+	require.InDelta(t, (1.0/61+1.0/63)/(2.0/61), fused[0].Score, 0.0001)
+	require.Equal(t, float32(1), fuseRankings(1, dense, dense)[0].Score, "top rank in all rankings")
+}
+
+func TestLimitKeywordOnlyHits(t *testing.T) {
+	doc := func(content string) schema.Document {
+		return schema.Document{PageContent: content, Metadata: map[string]any{"url": "https://example.org"}}
+	}
+
+	similar := []schema.Document{doc("a"), doc("b")}
+	keywords := []schema.Document{doc("x"), doc("b"), doc("y"), doc("a"), doc("z")}
+
+	limited := limitKeywordOnlyHits(keywords, similar, 1)
+
+	contents := make([]string, len(limited))
+	for i, d := range limited {
+		contents[i] = d.PageContent
+	}
+
+	require.Equal(t, []string{"x", "b", "a"}, contents)
+}
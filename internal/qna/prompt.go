@@ -0,0 +1,155 @@
+package qna
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultPromptTemplate is the system prompt template used when no custom template is configured.
+const DefaultPromptTemplate = `
+You are an AI knowledge bot whose purpose is to help users deepen their understanding of a specific topic.
+Your domain expertise is "{{.Topic}}" and you can assume that all user questions relate to this topic.
+
+Role:
+- You are a helpful assistant.
+- Answer the user’s questions briefly and concisely.
+- Use simple, everyday language and avoid unnecessary technical details.
+- When deeper explanations are required, ask follow-up questions to clarify the user’s needs.
+- Cite the sources your answer is based on by their number in square brackets, e.g. [1] or [1, 2], directly after the corresponding statement.
+- Only cite the numbered sources listed below.
+
+Here is the related data for the user’s question, numbered by source:
+{{range .Sources}}
+[{{.Number}}] {{.Title}}
+{{.Text}}
+{{end}}
+`
+
+// PromptTemplate renders the system prompt using Go template syntax.
+type PromptTemplate struct {
+	tmpl *template.Template
+}
+
+// PromptData is the data a PromptTemplate is rendered with.
+type PromptData struct {
+	Question string
+	Topic    string
+	// Date is the current time.
+	Date    time.Time
+	Sources []PromptSource
+	// Vars holds custom variables.
+	Vars map[string]string
+}
+
+// PromptSource is a source that the LLM can cite by its number.
+type PromptSource struct {
+	Number int
+	Title  string
+	URL    string
+	Score  float32
+	// Text holds the texts of the source's snippets.
+	Text     string
+	Snippets []Snippet
+}
+
+// ParsePromptTemplate parses the given Go template.
+func ParsePromptTemplate(text string) (*PromptTemplate, error) {
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse prompt template: %w", err)
+	}
+
+	return &PromptTemplate{tmpl: tmpl}, nil
+}
+
+// LoadPromptTemplate loads a Go template from the given file.
+func LoadPromptTemplate(file string) (*PromptTemplate, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("load prompt template: %w", err)
+	}
+
+	return ParsePromptTemplate(string(b))
+}
+
+// Validate renders the template with sample data in order to detect errors such as unknown fields or variables early.
+func (t *PromptTemplate) Validate(topic string, vars map[string]string) error {
+	_, err := t.Execute(PromptData{
+		Question: "What is the question?",
+		Topic:    topic,
+		Date:     time.Now(),
+		Sources: []PromptSource{{
+			Number:   1,
+			Title:    "Example",
+			URL:      "https://example.org",
+			Score:    1,
+			Text:     "Example text.",
+			Snippets: []Snippet{{Text: "Example text.", Score: 1}},
+		}},
+		Vars: vars,
+	})
+
+	return err
+}
+
+// Execute renders the template using the given data.
+func (t *PromptTemplate) Execute(data PromptData) (string, error) {
+	if data.Vars == nil {
+		data.Vars = map[string]string{}
+	}
+
+	var b strings.Builder
+
+	err := t.tmpl.Execute(&b, data)
+	if err != nil {
+		return "", fmt.Errorf("render prompt template: %w", err)
+	}
+
+	return b.String(), nil
+}
+
+// buildPrompt returns the system prompt containing the snippets of the given sources, numbered so that the LLM can cite them.
+func (w *QuestionAnswerWorkflow) buildPrompt(topic, question string, sources []SourceReference) (string, error) {
+	tmpl := w.PromptTemplate
+	if tmpl == nil {
+		tmpl = defaultPromptTemplate
+	}
+
+	promptSources := make([]PromptSource, len(sources))
+
+	for i, src := range sources {
+		texts := make([]string, len(src.Snippets))
+		for j, snippet := range src.Snippets {
+			texts[j] = snippet.Text
+		}
+
+		promptSources[i] = PromptSource{
+			Number:   i + 1,
+			Title:    src.Title,
+			URL:      src.URL,
+			Score:    src.MaxScore,
+			Text:     strings.Join(texts, "\n\n"),
+			Snippets: src.Snippets,
+		}
+	}
+
+	return tmpl.Execute(PromptData{
+		Question: question,
+		Topic:    topic,
+		Date:     time.Now(),
+		Sources:  promptSources,
+		Vars:     w.PromptVars,
+	})
+}
+
+var defaultPromptTemplate = func() *PromptTemplate {
+	t, err := ParsePromptTemplate(DefaultPromptTemplate)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}()
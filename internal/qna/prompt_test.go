@@ -0,0 +1,70 @@
+package qna
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptTemplate(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		template    string
+		vars        map[string]string
+		expect      string
+		expectError bool
+	}{
+		{
+			name:     "sources and vars",
+			template: `Answer in {{.Vars.language}} about {{.Topic}}.{{range .Sources}} [{{.Number}}] {{.Title}} ({{.URL}}): {{.Text}}{{end}}`,
+			vars:     map[string]string{"language": "German"},
+			expect:   "Answer in German about Futurama. [1] Fry (https://example.org/fry): Fry is a delivery boy.\n\nFry is voiced by Billy West.",
+		},
+		{
+			name:        "unknown field",
+			template:    `{{.Unknown}}`,
+			expectError: true,
+		},
+		{
+			name:        "missing var",
+			template:    `{{.Vars.language}}`,
+			expectError: true,
+		},
+		{
+			name:        "syntax error",
+			template:    `{{.Topic`,
+			expectError: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, err := ParsePromptTemplate(tc.template)
+			if err == nil {
+				err = tmpl.Validate("Futurama", tc.vars)
+			}
+
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			w := &QuestionAnswerWorkflow{Topic: "Futurama", PromptTemplate: tmpl, PromptVars: tc.vars}
+
+			prompt, err := w.buildPrompt("Futurama", "Who is Fry?", []SourceReference{{
+				URL:   "https://example.org/fry",
+				Title: "Fry",
+				Snippets: []Snippet{
+					{Text: "Fry is a delivery boy."},
+					{Text: "Fry is voiced by Billy West."},
+				},
+			}})
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, prompt)
+		})
+	}
+}
+
+func TestDefaultPromptTemplate(t *testing.T) {
+	require.NoError(t, defaultPromptTemplate.Validate("Futurama", nil))
+}
@@ -5,35 +5,17 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"strings"
 
+	"github.com/mgoltzsche/knowledgebot/internal/tokens"
 	"github.com/tmc/langchaingo/llms"
-	"github.com/tmc/langchaingo/prompts"
 	"github.com/tmc/langchaingo/schema"
 	"github.com/tmc/langchaingo/vectorstores"
 )
 
-var (
-	tmpl string = `
-You are an AI knowledge bot whose purpose is to help users deepen their understanding of a specific topic.
-Your domain expertise is "{{.topic}}" and you can assume that all user questions relate to this topic.
-
-Role:
-- You are a helpful assistant.
-- Answer the user’s questions briefly and concisely.
-- Use simple, everyday language and avoid unnecessary technical details.
-- When deeper explanations are required, ask follow-up questions to clarify the user’s needs.
-
-Here is the related data for the user’s question:
-{{ .sources }}
-	`
-
-	promptTemplate prompts.PromptTemplate = prompts.PromptTemplate{
-		Template:       tmpl,
-		InputVariables: []string{"sources"},
-		TemplateFormat: prompts.TemplateFormatGoTemplate,
-	}
-)
+// ErrInvalidRequest is returned when a request specifies invalid parameters.
+var ErrInvalidRequest = errors.New("invalid request")
 
 type QuestionAnswerWorkflow struct {
 	LLM            llms.Model
@@ -42,12 +24,38 @@ type QuestionAnswerWorkflow struct {
 	MaxDocs        int
 	ScoreThreshold float64
 	Topic          string
+	// MaxDocsLimit is the maximum number of document chunks a request can ask for, 0 for unlimited.
+	MaxDocsLimit int
+	// MaxTemperature is the maximum temperature a request can ask for, 0 for unlimited.
+	MaxTemperature float64
+	// PromptTemplate renders the system prompt, DefaultPromptTemplate is used if nil.
+	PromptTemplate *PromptTemplate
+	// PromptVars are custom variables that are passed to the PromptTemplate.
+	PromptVars map[string]string
+	// MaxHistory is the maximum number of previous conversation messages passed to the LLM, -1 for unlimited.
+	MaxHistory int
+	// HybridSearch combines the vector similarity search results with keyword search results.
+	// It requires the Store to implement KeywordSearcher.
+	HybridSearch bool
+	// Reranker optionally reorders the retrieved documents before the top MaxDocs are passed to the LLM.
+	Reranker Reranker
+	// RerankCandidates is the number of documents retrieved for the Reranker to choose from.
+	RerankCandidates int
+	// ContextTokens is the size of the LLM's context window in tokens that the prompt, history and answer must fit into.
+	// Retrieved chunks that exceed it are dropped, 0 disables the check.
+	ContextTokens int
+	// AnswerTokens is the number of tokens reserved for the answer within the context window.
+	AnswerTokens int
 }
 
 type ResponseChunk struct {
-	Err     error             `json:"error,omitempty"`
-	Chunk   string            `json:"chunk,omitempty"`
-	Sources []SourceReference `json:"sources,omitempty"`
+	Err            error             `json:"error,omitempty"`
+	ConversationID string            `json:"conversationId,omitempty"`
+	Chunk          string            `json:"chunk,omitempty"`
+	Citation       *Citation         `json:"citation,omitempty"`
+	Sources        []SourceReference `json:"sources,omitempty"`
+	// Dropped lists the retrieved chunks that were not passed to the LLM since they exceeded the context budget.
+	Dropped []SourceReference `json:"dropped,omitempty"`
 }
 
 type SourceReference struct {
@@ -60,24 +68,56 @@ type SourceReference struct {
 type Snippet struct {
 	Text  string  `json:"text"`
 	Score float32 `json:"score"`
+	// Truncated indicates that the text was shortened to fit into the context budget.
+	Truncated bool `json:"truncated,omitempty"`
 }
 
-func (w *QuestionAnswerWorkflow) Answer(ctx context.Context, question string) (<-chan ResponseChunk, error) {
-	docs, err := w.Store.SimilaritySearch(ctx, question, w.MaxDocs, vectorstores.WithScoreThreshold(float32(w.ScoreThreshold)))
+// Answer answers the given question, taking the previous messages of the conversation into account.
+func (w *QuestionAnswerWorkflow) Answer(ctx context.Context, req Request) (<-chan ResponseChunk, error) {
+	err := req.Validate()
 	if err != nil {
-		return nil, fmt.Errorf("query knowledge base: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 	}
 
-	sourceRefs := searchResultsToSourceRefs(docs)
+	question, history := req.Question, req.History
+	params := w.params(&req)
 
-	ch := make(chan ResponseChunk)
+	historyMsgs, err := w.historyMessages(history)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := w.standaloneQuestion(ctx, question, history)
+	if err != nil {
+		return nil, err
+	}
 
-	promptTemplate.PartialVariables = map[string]any{
-		"topic": w.Topic,
+	if query != question {
+		slog.Info("rewrote follow-up question", "question", question, "query", query)
 	}
 
-	prompt, err := buildPrompt(docs)
+	docs, err := w.search(ctx, query, params.maxDocs, params.scoreThreshold, params.filters)
+	if err != nil {
+		return nil, fmt.Errorf("query knowledge base: %w", err)
+	}
 
+	emptyPrompt, err := w.buildPrompt(params.topic, question, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	fit := w.fitContext(docs, w.reservedTokens(tokens.Estimate(emptyPrompt), question, history))
+	if len(fit.dropped) > 0 || len(fit.truncated) > 0 {
+		slog.Warn(fmt.Sprintf("dropped %d and truncated %d of %d retrieved chunks to fit into the context budget of %d tokens",
+			len(fit.dropped), len(fit.truncated), len(docs), w.ContextTokens))
+	}
+
+	sourceRefs := searchResultsToSourceRefs(fit.docs, fit.truncated)
+	droppedRefs := searchResultsToSourceRefs(fit.dropped, nil)
+
+	ch := make(chan ResponseChunk)
+
+	prompt, err := w.buildPrompt(params.topic, question, sourceRefs)
 	if err != nil {
 		return nil, err
 	}
@@ -87,41 +127,54 @@ func (w *QuestionAnswerWorkflow) Answer(ctx context.Context, question string) (<
 	go func() {
 		defer close(ch)
 
-		if len(sourceRefs) > 0 {
-			ch <- ResponseChunk{Sources: sourceRefs}
+		if len(sourceRefs) > 0 || len(droppedRefs) > 0 {
+			ch <- ResponseChunk{Sources: sourceRefs, Dropped: droppedRefs}
 		}
 
-		_, err := w.LLM.GenerateContent(ctx,
-			[]llms.MessageContent{
-				llms.TextParts(llms.ChatMessageTypeSystem, prompt),
-				llms.TextParts(llms.ChatMessageTypeHuman, question),
-			},
-			llms.WithStreamingFunc(w.streamFunc(ch)),
-			llms.WithTemperature(w.Temperature),
+		msgs := make([]llms.MessageContent, 0, len(historyMsgs)+2)
+		msgs = append(msgs, llms.TextParts(llms.ChatMessageTypeSystem, prompt))
+		msgs = append(msgs, historyMsgs...)
+		msgs = append(msgs, llms.TextParts(llms.ChatMessageTypeHuman, question))
+
+		citations := newCitationParser(sourceRefs)
+
+		_, err := w.LLM.GenerateContent(ctx, msgs,
+			llms.WithStreamingFunc(w.streamFunc(ch, citations)),
+			llms.WithTemperature(params.temperature),
 		)
-		if err != nil && !errors.Is(err, context.Canceled) {
-			ch <- ResponseChunk{Err: err}
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				ch <- ResponseChunk{Err: err}
+			}
+
+			return
+		}
+
+		for _, c := range citations.Flush() {
+			ch <- c
 		}
 	}()
 
 	return ch, nil
 }
 
-func (w *QuestionAnswerWorkflow) streamFunc(ch chan<- ResponseChunk) func(ctx context.Context, chunk []byte) error {
+func (w *QuestionAnswerWorkflow) streamFunc(ch chan<- ResponseChunk, citations *citationParser) func(ctx context.Context, chunk []byte) error {
 	return func(ctx context.Context, chunk []byte) error {
-		if len(chunk) > 0 {
-			ch <- ResponseChunk{Chunk: string(chunk)}
+		for _, c := range citations.Parse(string(chunk)) {
+			ch <- c
 		}
 
 		return nil
 	}
 }
 
-func searchResultsToSourceRefs(docs []schema.Document) []SourceReference {
+// searchResultsToSourceRefs groups the given documents by source.
+// The truncated map holds the indices of the documents whose text was truncated.
+func searchResultsToSourceRefs(docs []schema.Document, truncated map[int]bool) []SourceReference {
 	urlMap := make(map[string]*SourceReference, len(docs))
 	urls := make([]string, 0, len(docs))
 
-	for _, doc := range docs {
+	for i, doc := range docs {
 		urlKey, ok := doc.Metadata["url"].(string)
 		if !ok {
 			slog.Warn("vectordb search result doc does not specify 'url' metadata key")
@@ -134,6 +187,17 @@ func searchResultsToSourceRefs(docs []schema.Document) []SourceReference {
 			continue
 		}
 
+		if page, ok := pageNumber(doc.Metadata["page"]); ok {
+			urlKey = fmt.Sprintf("%s#page=%d", urlKey, page)
+			title = fmt.Sprintf("%s (page %d)", title, page)
+		} else if anchor, _ := doc.Metadata["anchor"].(string); anchor != "" {
+			urlKey = fmt.Sprintf("%s#%s", urlKey, url.PathEscape(anchor))
+
+			if section, _ := doc.Metadata["section"].(string); section != "" {
+				title = fmt.Sprintf("%s (%s)", title, section)
+			}
+		}
+
 		ref, ok := urlMap[urlKey]
 		if !ok {
 			ref = &SourceReference{
@@ -146,8 +210,9 @@ func searchResultsToSourceRefs(docs []schema.Document) []SourceReference {
 		}
 
 		ref.Snippets = append(ref.Snippets, Snippet{
-			Text:  doc.PageContent,
-			Score: doc.Score,
+			Text:      doc.PageContent,
+			Score:     doc.Score,
+			Truncated: truncated[i],
 		})
 
 		if doc.Score > ref.MaxScore {
@@ -163,18 +228,17 @@ func searchResultsToSourceRefs(docs []schema.Document) []SourceReference {
 	return refs
 }
 
-func buildPrompt(docs []schema.Document) (string, error) {
-	related := make([]string, len(docs))
-	for i, doc := range docs {
-		related[i] = doc.PageContent
+// pageNumber returns the page number of a paginated document's chunk, if present.
+// Numbers are represented as float64 when the metadata was unmarshalled from JSON.
+func pageNumber(v any) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case float64:
+		return int(n), true
+	default:
+		return 0, false
 	}
-
-	result, err := promptTemplate.Format(map[string]any{
-		"sources": strings.Join(related, "\n\n"),
-	})
-	if err != nil {
-		return "", fmt.Errorf("formatting promt template: %w", err)
-	}
-
-	return result, nil
 }
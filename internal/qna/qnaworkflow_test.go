@@ -0,0 +1,112 @@
+package qna
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+type fakeLLM struct {
+	calls [][]llms.MessageContent
+}
+
+func (l *fakeLLM) GenerateContent(ctx context.Context, msgs []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	l.calls = append(l.calls, msgs)
+
+	opts := llms.CallOptions{}
+	for _, o := range options {
+		o(&opts)
+	}
+
+	answer := "Billy West"
+	if msgs[0].Parts[0] == (llms.TextContent{Text: standaloneQuestionPrompt}) {
+		answer = "Who voiced Fry?"
+	}
+
+	if opts.StreamingFunc != nil {
+		err := opts.StreamingFunc(ctx, []byte(answer))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: answer}}}, nil
+}
+
+func (l *fakeLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, l, prompt, options...)
+}
+
+type fakeStore struct {
+	queries []string
+}
+
+func (s *fakeStore) AddDocuments(context.Context, []schema.Document, ...vectorstores.Option) ([]string, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) SimilaritySearch(_ context.Context, query string, _ int, _ ...vectorstores.Option) ([]schema.Document, error) {
+	s.queries = append(s.queries, query)
+
+	return []schema.Document{{
+		PageContent: "Fry is voiced by Billy West.",
+		Metadata:    map[string]any{"url": "https://example.org/fry", "title": "Fry"},
+		Score:       0.8,
+	}}, nil
+}
+
+func TestAnswerFollowUpQuestion(t *testing.T) {
+	llm := &fakeLLM{}
+	store := &fakeStore{}
+	w := &QuestionAnswerWorkflow{LLM: llm, Store: store, MaxDocs: 5, MaxHistory: 10}
+	history := []Message{
+		{Role: RoleUser, Content: "Who is Fry?"},
+		{Role: RoleAssistant, Content: "Fry is a delivery boy."},
+	}
+
+	ch, err := w.Answer(context.Background(), Request{Question: "And who voiced him?", History: history})
+	require.NoError(t, err)
+
+	var chunks []ResponseChunk
+	for chunk := range ch {
+		chunks = append(chunks, chunk)
+	}
+
+	require.Equal(t, []string{"Who voiced Fry?"}, store.queries, "search query")
+	require.Len(t, chunks, 2)
+	require.Len(t, chunks[0].Sources, 1)
+	require.Equal(t, "Billy West", chunks[1].Chunk)
+	require.Len(t, llm.calls, 2)
+
+	roles := make([]llms.ChatMessageType, len(llm.calls[1]))
+	for i, m := range llm.calls[1] {
+		roles[i] = m.Role
+	}
+
+	require.Equal(t, []llms.ChatMessageType{
+		llms.ChatMessageTypeSystem,
+		llms.ChatMessageTypeHuman,
+		llms.ChatMessageTypeAI,
+		llms.ChatMessageTypeHuman,
+	}, roles, "answer message roles")
+	require.Equal(t, llms.TextContent{Text: "And who voiced him?"}, llm.calls[1][3].Parts[0])
+}
+
+func TestAnswerWithoutHistory(t *testing.T) {
+	llm := &fakeLLM{}
+	store := &fakeStore{}
+	w := &QuestionAnswerWorkflow{LLM: llm, Store: store, MaxDocs: 5, MaxHistory: 10}
+
+	ch, err := w.Answer(context.Background(), Request{Question: "Who voiced Fry?"})
+	require.NoError(t, err)
+
+	for range ch {
+	}
+
+	require.Equal(t, []string{"Who voiced Fry?"}, store.queries, "search query")
+	require.Len(t, llm.calls, 1, "LLM calls")
+}
@@ -0,0 +1,111 @@
+package qna
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxTopicLength is the maximum length of a topic specified per request.
+const maxTopicLength = 200
+
+// Request is a question along with optional parameters that override the workflow's defaults for this request.
+type Request struct {
+	Question string `json:"question"`
+	// History holds the previous messages of the conversation.
+	History []Message `json:"history,omitempty"`
+	// Temperature overrides the LLM temperature, limited to the workflow's MaxTemperature.
+	Temperature *float64 `json:"temperature,omitempty"`
+	// MaxDocs overrides the maximum number of retrieved chunks, limited to the workflow's MaxDocsLimit.
+	MaxDocs *int `json:"maxDocs,omitempty"`
+	// ScoreThreshold overrides the minimum similarity score of the retrieved chunks.
+	ScoreThreshold *float64 `json:"scoreThreshold,omitempty"`
+	// Topic overrides the topic that is passed to the prompt template.
+	Topic string `json:"topic,omitempty"`
+	// Filters restricts the retrieval to chunks whose metadata values equal the given ones, e.g. {"url": "https://example.org"}.
+	Filters map[string]any `json:"filters,omitempty"`
+}
+
+// requestParams are the parameters that apply to a request.
+type requestParams struct {
+	temperature    float64
+	maxDocs        int
+	scoreThreshold float64
+	topic          string
+	filters        map[string]any
+}
+
+// Validate returns an error if the request is invalid.
+func (r *Request) Validate() error {
+	if strings.TrimSpace(r.Question) == "" {
+		return errors.New("no question specified")
+	}
+
+	for _, m := range r.History {
+		if m.Role != RoleUser && m.Role != RoleAssistant {
+			return fmt.Errorf("unsupported message role %q", m.Role)
+		}
+	}
+
+	if r.Temperature != nil && *r.Temperature < 0 {
+		return errors.New("temperature must not be negative")
+	}
+
+	if r.MaxDocs != nil && *r.MaxDocs < 1 {
+		return errors.New("maxDocs must be at least 1")
+	}
+
+	if r.ScoreThreshold != nil && (*r.ScoreThreshold < 0 || *r.ScoreThreshold > 1) {
+		return errors.New("scoreThreshold must be between 0 and 1")
+	}
+
+	if len(r.Topic) > maxTopicLength {
+		return fmt.Errorf("topic must not be longer than %d characters", maxTopicLength)
+	}
+
+	for k, v := range r.Filters {
+		switch v.(type) {
+		case string, bool, int, int64, float64:
+		default:
+			return fmt.Errorf("filter %q must specify a string, number or boolean value", k)
+		}
+	}
+
+	return nil
+}
+
+// params returns the parameters that apply to the given request.
+// Overrides that exceed the workflow's limits are capped.
+func (w *QuestionAnswerWorkflow) params(req *Request) requestParams {
+	p := requestParams{
+		temperature:    w.Temperature,
+		maxDocs:        w.MaxDocs,
+		scoreThreshold: w.ScoreThreshold,
+		topic:          w.Topic,
+		filters:        req.Filters,
+	}
+
+	if req.Temperature != nil {
+		p.temperature = *req.Temperature
+		if w.MaxTemperature > 0 {
+			p.temperature = min(p.temperature, w.MaxTemperature)
+		}
+	}
+
+	if req.MaxDocs != nil {
+		p.maxDocs = *req.MaxDocs
+		if w.MaxDocsLimit > 0 {
+			p.maxDocs = min(p.maxDocs, w.MaxDocsLimit)
+		}
+	}
+
+	if req.ScoreThreshold != nil {
+		p.scoreThreshold = *req.ScoreThreshold
+	}
+
+	if req.Topic != "" {
+		p.topic = req.Topic
+	}
+
+	return p
+}
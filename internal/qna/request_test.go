@@ -0,0 +1,86 @@
+package qna
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestParams(t *testing.T) {
+	w := &QuestionAnswerWorkflow{
+		Temperature:    0.7,
+		MaxDocs:        15,
+		ScoreThreshold: 0.5,
+		Topic:          "Futurama",
+		MaxDocsLimit:   20,
+		MaxTemperature: 1,
+	}
+	float := func(v float64) *float64 { return &v }
+	maxDocs := func(v int) *int { return &v }
+
+	for _, tc := range []struct {
+		name        string
+		req         Request
+		expect      requestParams
+		expectError bool
+	}{
+		{
+			name:   "defaults",
+			req:    Request{Question: "q"},
+			expect: requestParams{temperature: 0.7, maxDocs: 15, scoreThreshold: 0.5, topic: "Futurama"},
+		},
+		{
+			name: "overrides",
+			req: Request{
+				Question:       "q",
+				Temperature:    float(0),
+				MaxDocs:        maxDocs(5),
+				ScoreThreshold: float(0.8),
+				Topic:          "Simpsons",
+				Filters:        map[string]any{"url": "https://example.org"},
+			},
+			expect: requestParams{temperature: 0, maxDocs: 5, scoreThreshold: 0.8, topic: "Simpsons", filters: map[string]any{"url": "https://example.org"}},
+		},
+		{
+			name:   "capped overrides",
+			req:    Request{Question: "q", Temperature: float(2), MaxDocs: maxDocs(100)},
+			expect: requestParams{temperature: 1, maxDocs: 20, scoreThreshold: 0.5, topic: "Futurama"},
+		},
+		{
+			name:        "no question",
+			req:         Request{Question: " "},
+			expectError: true,
+		},
+		{
+			name:        "invalid max docs",
+			req:         Request{Question: "q", MaxDocs: maxDocs(0)},
+			expectError: true,
+		},
+		{
+			name:        "invalid score threshold",
+			req:         Request{Question: "q", ScoreThreshold: float(1.5)},
+			expectError: true,
+		},
+		{
+			name:        "invalid history role",
+			req:         Request{Question: "q", History: []Message{{Role: "system", Content: "c"}}},
+			expectError: true,
+		},
+		{
+			name:        "invalid filter value",
+			req:         Request{Question: "q", Filters: map[string]any{"url": []any{"a", "b"}}},
+			expectError: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.req.Validate()
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, w.params(&tc.req))
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package qna
+
+import (
+	"context"
+
+	"github.com/tmc/langchaingo/schema"
+)
+
+// Reranker reorders retrieved documents by their relevance for a query.
+type Reranker interface {
+	// Rerank returns the given documents ordered by descending relevance, with their scores set to the relevance scores.
+	Rerank(ctx context.Context, query string, docs []schema.Document) ([]schema.Document, error)
+}
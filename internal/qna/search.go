@@ -0,0 +1,125 @@
+package qna
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// SearchRequest specifies a knowledge base search.
+type SearchRequest struct {
+	Query string
+	// Limit is the maximum number of chunks to return.
+	Limit int
+	// Offset is the number of top-ranked chunks to skip.
+	Offset int
+	// ScoreThreshold is the minimum similarity score of the chunks.
+	ScoreThreshold float64
+	// Filters restricts the search to chunks whose metadata values equal the given ones.
+	Filters map[string]any
+}
+
+// SearchResponse holds a page of the ranked search results.
+type SearchResponse struct {
+	Sources []SourceReference `json:"sources"`
+	Offset  int               `json:"offset"`
+	Limit   int               `json:"limit"`
+	// HasMore indicates that there are more results after the returned page.
+	HasMore bool `json:"hasMore"`
+}
+
+// Search returns the chunks that are relevant for the given query, grouped by source,
+// using the same retrieval as Answer but without asking the LLM.
+// The limit is capped to the workflow's MaxDocsLimit.
+// When a Reranker is configured, only the RerankCandidates top-ranked chunks can be paged through
+// so that a request cannot make the Reranker process an arbitrary number of chunks.
+func (w *QuestionAnswerWorkflow) Search(ctx context.Context, req SearchRequest) (*SearchResponse, error) {
+	limit := req.Limit
+	if w.MaxDocsLimit > 0 {
+		limit = min(limit, w.MaxDocsLimit)
+	}
+
+	candidates := req.Offset + limit + 1
+	if w.Reranker != nil && w.RerankCandidates > 0 {
+		candidates = min(candidates, w.RerankCandidates)
+	}
+
+	resp := &SearchResponse{
+		Sources: []SourceReference{},
+		Offset:  req.Offset,
+		Limit:   limit,
+	}
+
+	if req.Offset >= candidates {
+		return resp, nil
+	}
+
+	docs, err := w.search(ctx, req.Query, candidates, req.ScoreThreshold, req.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("query knowledge base: %w", err)
+	}
+
+	resp.HasMore = len(docs) > req.Offset+limit
+
+	if req.Offset < len(docs) {
+		page := docs[req.Offset:min(req.Offset+limit, len(docs))]
+		resp.Sources = searchResultsToSourceRefs(page, nil)
+	}
+
+	return resp, nil
+}
+
+// search returns the limit most relevant chunks for the given query.
+// With hybrid search, the score threshold applies to the similarity search results,
+// keyword hits that were not found by the similarity search are limited to a share of the candidates
+// and the returned scores are normalized fused ranks instead of similarities.
+// The filters restrict the search to chunks whose metadata values equal the given ones.
+func (w *QuestionAnswerWorkflow) search(ctx context.Context, query string, limit int, scoreThreshold float64, filters map[string]any) ([]schema.Document, error) {
+	candidates := limit
+	if w.Reranker != nil {
+		candidates = max(w.RerankCandidates, limit)
+	}
+
+	var filterOpts []vectorstores.Option
+	if len(filters) > 0 {
+		filterOpts = append(filterOpts, vectorstores.WithFilters(filters))
+	}
+
+	opts := append([]vectorstores.Option{vectorstores.WithScoreThreshold(float32(scoreThreshold))}, filterOpts...)
+
+	docs, err := w.Store.SimilaritySearch(ctx, query, candidates, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.HybridSearch {
+		searcher, ok := w.Store.(KeywordSearcher)
+		if !ok {
+			return nil, errors.New("the vector store does not support keyword search")
+		}
+
+		keywordDocs, err := searcher.KeywordSearch(ctx, query, candidates, filterOpts...)
+		if err != nil {
+			return nil, err
+		}
+
+		// Keyword hits have no similarity score the threshold could be applied to.
+		if scoreThreshold > 0 {
+			keywordDocs = limitKeywordOnlyHits(keywordDocs, docs, max(1, candidates/keywordOnlyShare))
+		}
+
+		docs = fuseRankings(candidates, docs, keywordDocs)
+	}
+
+	if w.Reranker != nil && len(docs) > 0 {
+		docs, err = w.Reranker.Rerank(ctx, query, docs)
+		if err != nil {
+			return nil, fmt.Errorf("rerank: %w", err)
+		}
+	}
+
+	return docs[:min(limit, len(docs))], nil
+}
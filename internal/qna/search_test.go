@@ -0,0 +1,107 @@
+package qna
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+type rankedStore struct {
+	fakeStore
+	docs []schema.Document
+}
+
+func (s *rankedStore) SimilaritySearch(_ context.Context, _ string, n int, _ ...vectorstores.Option) ([]schema.Document, error) {
+	return s.docs[:min(n, len(s.docs))], nil
+}
+
+func TestSearch(t *testing.T) {
+	store := &rankedStore{}
+	for i := range 5 {
+		store.docs = append(store.docs, schema.Document{
+			PageContent: fmt.Sprintf("chunk %d", i),
+			Metadata:    map[string]any{"url": fmt.Sprintf("https://example.org/%d", i), "title": "Page"},
+			Score:       1 - float32(i)/10,
+		})
+	}
+
+	w := &QuestionAnswerWorkflow{Store: store}
+
+	for _, tc := range []struct {
+		name          string
+		limit, offset int
+		expectURLs    []string
+		expectHasMore bool
+	}{
+		{
+			name:          "first page",
+			limit:         2,
+			expectURLs:    []string{"https://example.org/0", "https://example.org/1"},
+			expectHasMore: true,
+		},
+		{
+			name:       "last page",
+			limit:      2,
+			offset:     4,
+			expectURLs: []string{"https://example.org/4"},
+		},
+		{
+			name:       "beyond the results",
+			limit:      2,
+			offset:     6,
+			expectURLs: []string{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := w.Search(context.Background(), SearchRequest{Query: "q", Limit: tc.limit, Offset: tc.offset})
+			require.NoError(t, err)
+
+			urls := make([]string, len(resp.Sources))
+			for i, src := range resp.Sources {
+				urls[i] = src.URL
+			}
+
+			require.Equal(t, tc.expectURLs, urls)
+			require.Equal(t, tc.expectHasMore, resp.HasMore, "hasMore")
+		})
+	}
+}
+
+type countingReranker struct {
+	reranked int
+}
+
+func (r *countingReranker) Rerank(_ context.Context, _ string, docs []schema.Document) ([]schema.Document, error) {
+	r.reranked += len(docs)
+	return docs, nil
+}
+
+func TestSearchLimitsRerankedWindow(t *testing.T) {
+	store := &rankedStore{}
+	for i := range 10 {
+		store.docs = append(store.docs, schema.Document{
+			PageContent: fmt.Sprintf("chunk %d", i),
+			Metadata:    map[string]any{"url": fmt.Sprintf("https://example.org/%d", i), "title": "Page"},
+		})
+	}
+
+	reranker := &countingReranker{}
+	w := &QuestionAnswerWorkflow{Store: store, Reranker: reranker, RerankCandidates: 4, MaxDocsLimit: 3}
+	ctx := context.Background()
+
+	resp, err := w.Search(ctx, SearchRequest{Query: "q", Limit: 100, Offset: 2})
+	require.NoError(t, err)
+	require.Equal(t, 3, resp.Limit, "limit should be capped to MaxDocsLimit")
+	require.Len(t, resp.Sources, 2, "page should end at the rerank candidates")
+	require.False(t, resp.HasMore, "hasMore")
+	require.Equal(t, 4, reranker.reranked, "reranked chunks")
+
+	resp, err = w.Search(ctx, SearchRequest{Query: "q", Limit: 2, Offset: 1000})
+	require.NoError(t, err)
+	require.Empty(t, resp.Sources)
+	require.Equal(t, 4, reranker.reranked, "offset beyond the rerank candidates should not rerank")
+}
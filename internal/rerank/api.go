@@ -0,0 +1,116 @@
+package rerank
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/mgoltzsche/knowledgebot/internal/qna"
+	"github.com/tmc/langchaingo/schema"
+)
+
+// APIReranker reranks documents using a Cohere or Jina compatible rerank API endpoint.
+type APIReranker struct {
+	// URL is the rerank endpoint URL, e.g. https://api.jina.ai/v1/rerank.
+	URL    string
+	APIKey string
+	Model  string
+	Client *http.Client
+}
+
+var _ qna.Reranker = &APIReranker{}
+
+type rerankRequest struct {
+	Model     string   `json:"model,omitempty"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+	TopN      int      `json:"top_n"`
+}
+
+type rerankResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float32 `json:"relevance_score"`
+	} `json:"results"`
+}
+
+// Rerank returns the given documents ordered by the relevance scores returned by the API.
+func (r *APIReranker) Rerank(ctx context.Context, query string, docs []schema.Document) ([]schema.Document, error) {
+	if len(docs) == 0 {
+		return docs, nil
+	}
+
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = doc.PageContent
+	}
+
+	body, err := json.Marshal(rerankRequest{
+		Model:     r.Model,
+		Query:     query,
+		Documents: texts,
+		TopN:      len(texts),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rerank: marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("rerank: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if r.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+r.APIKey)
+	}
+
+	client := r.Client
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("rerank: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("rerank: server responded with %s: %s", resp.Status, string(bytes.TrimSpace(msg)))
+	}
+
+	var result rerankResponse
+
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("rerank: decode response: %w", err)
+	}
+
+	reranked := make([]schema.Document, 0, len(result.Results))
+
+	for _, res := range result.Results {
+		if res.Index < 0 || res.Index >= len(docs) {
+			return nil, fmt.Errorf("rerank: response refers to unknown document index %d", res.Index)
+		}
+
+		doc := docs[res.Index]
+		doc.Score = res.RelevanceScore
+		reranked = append(reranked, doc)
+	}
+
+	slices.SortStableFunc(reranked, func(a, b schema.Document) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+
+	return reranked, nil
+}
@@ -0,0 +1,42 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+)
+
+func TestAPIReranker(t *testing.T) {
+	var req rerankRequest
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		_, _ = w.Write([]byte(`{"results":[{"index":1,"relevance_score":0.9},{"index":0,"relevance_score":0.2}]}`))
+	}))
+	defer srv.Close()
+
+	r := &APIReranker{URL: srv.URL, APIKey: "secret", Model: "rerank-model"}
+	docs := []schema.Document{
+		{PageContent: "Leela is the captain.", Score: 0.8},
+		{PageContent: "Fry is voiced by Billy West.", Score: 0.7},
+	}
+
+	reranked, err := r.Rerank(context.Background(), "Who voiced Fry?", docs)
+	require.NoError(t, err)
+	require.Equal(t, rerankRequest{
+		Model:     "rerank-model",
+		Query:     "Who voiced Fry?",
+		Documents: []string{"Leela is the captain.", "Fry is voiced by Billy West."},
+		TopN:      2,
+	}, req, "request")
+	require.Equal(t, []schema.Document{
+		{PageContent: "Fry is voiced by Billy West.", Score: 0.9},
+		{PageContent: "Leela is the captain.", Score: 0.2},
+	}, reranked)
+}
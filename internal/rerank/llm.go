@@ -0,0 +1,122 @@
+// Package rerank provides implementations of qna.Reranker.
+package rerank
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mgoltzsche/knowledgebot/internal/qna"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+)
+
+const (
+	// defaultBatchSize is the number of documents the LLM is asked to score within a single request by default.
+	defaultBatchSize = 10
+	// maxDocumentLength is the maximum number of characters of a document that are passed to the LLM.
+	maxDocumentLength = 1000
+
+	scorePrompt = `You are a search relevance judge.
+Rate how relevant each of the numbered documents below is for answering the user's question on a scale from 0 (irrelevant) to 10 (answers the question directly).
+Respond with one line per document in the format "<document number>: <score>" and nothing else.`
+)
+
+var scoreLineRegex = regexp.MustCompile(`(?mi)^[^\w\n]*(?:document\s*)?(\d+)[^\w\n]*?[:=-]\s*(\d+(?:\.\d+)?)`)
+
+// LLMReranker reranks documents by asking an LLM to score their relevance.
+type LLMReranker struct {
+	LLM llms.Model
+	// BatchSize is the number of documents scored within a single LLM request.
+	BatchSize int
+}
+
+var _ qna.Reranker = &LLMReranker{}
+
+// Rerank returns the given documents ordered by their relevance for the query, as judged by the LLM.
+// The document scores are replaced with the LLM's scores, normalized to the range [0, 1].
+func (r *LLMReranker) Rerank(ctx context.Context, query string, docs []schema.Document) ([]schema.Document, error) {
+	batchSize := r.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	result := slices.Clone(docs)
+
+	for start := 0; start < len(result); start += batchSize {
+		batch := result[start:min(start+batchSize, len(result))]
+
+		scores, err := r.score(ctx, query, batch)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range batch {
+			batch[i].Score = scores[i]
+		}
+	}
+
+	slices.SortStableFunc(result, func(a, b schema.Document) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+
+	return result, nil
+}
+
+func (r *LLMReranker) score(ctx context.Context, query string, docs []schema.Document) ([]float32, error) {
+	var prompt strings.Builder
+
+	_, _ = fmt.Fprintf(&prompt, "Question: %s\n\n", query)
+
+	for i, doc := range docs {
+		text := doc.PageContent
+		if r := []rune(text); len(r) > maxDocumentLength {
+			text = string(r[:maxDocumentLength]) + "..."
+		}
+
+		_, _ = fmt.Fprintf(&prompt, "Document %d:\n%s\n\n", i+1, text)
+	}
+
+	resp, err := r.LLM.GenerateContent(ctx,
+		[]llms.MessageContent{
+			llms.TextParts(llms.ChatMessageTypeSystem, scorePrompt),
+			llms.TextParts(llms.ChatMessageTypeHuman, prompt.String()),
+		},
+		llms.WithTemperature(0),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("llm rerank: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("llm rerank: no response choices")
+	}
+
+	return parseScores(resp.Choices[0].Content, len(docs)), nil
+}
+
+// parseScores extracts the document scores from the LLM's response.
+// Documents the LLM did not score get a score of 0.
+func parseScores(response string, docCount int) []float32 {
+	scores := make([]float32, docCount)
+
+	for _, m := range scoreLineRegex.FindAllStringSubmatch(response, -1) {
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n < 1 || n > docCount {
+			continue
+		}
+
+		score, err := strconv.ParseFloat(m[2], 32)
+		if err != nil {
+			continue
+		}
+
+		scores[n-1] = float32(min(max(score, 0), 10) / 10)
+	}
+
+	return scores
+}
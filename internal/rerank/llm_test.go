@@ -0,0 +1,40 @@
+package rerank
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScores(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		response string
+		expect   []float32
+	}{
+		{
+			name:     "one score per line",
+			response: "1: 3\n2: 10\n3: 0",
+			expect:   []float32{0.3, 1, 0},
+		},
+		{
+			name:     "decorated lines",
+			response: "Here are the scores:\n- Document 2 = 7.5\n* 1 - 2",
+			expect:   []float32{0.2, 0.75, 0},
+		},
+		{
+			name:     "out of range",
+			response: "4: 9\n1: 42",
+			expect:   []float32{1, 0, 0},
+		},
+		{
+			name:     "no scores",
+			response: "I cannot judge this.",
+			expect:   []float32{0, 0, 0},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expect, parseScores(tc.response, 3))
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgoltzsche/knowledgebot/internal/qna"
+)
+
+const (
+	// conversationTTL is the duration after which an inactive conversation is forgotten.
+	conversationTTL = time.Hour
+	// maxConversations is the maximum number of conversations kept in memory.
+	maxConversations = 1000
+	// maxConversationMessages is the maximum number of messages kept per conversation.
+	maxConversationMessages = 50
+)
+
+// conversationStore keeps the message history of conversations in memory.
+type conversationStore struct {
+	mutex         sync.Mutex
+	conversations map[string]*conversation
+}
+
+type conversation struct {
+	messages   []qna.Message
+	lastAccess time.Time
+}
+
+func newConversationStore() *conversationStore {
+	return &conversationStore{conversations: map[string]*conversation{}}
+}
+
+// History returns a copy of the messages of the given conversation.
+// It returns false if the conversation does not exist (anymore).
+func (s *conversationStore) History(id string) ([]qna.Message, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	c, ok := s.conversations[id]
+	if !ok || time.Since(c.lastAccess) > conversationTTL {
+		return nil, false
+	}
+
+	c.lastAccess = time.Now()
+
+	return slices.Clone(c.messages), true
+}
+
+// NewConversation creates a new empty conversation and returns its ID.
+func (s *conversationStore) NewConversation() string {
+	id := uuid.NewString()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.evict()
+	s.conversations[id] = &conversation{lastAccess: time.Now()}
+
+	return id
+}
+
+// Append adds the given messages to the given conversation.
+func (s *conversationStore) Append(id string, messages ...qna.Message) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	c, ok := s.conversations[id]
+	if !ok {
+		s.evict()
+
+		c = &conversation{}
+		s.conversations[id] = c
+	}
+
+	c.messages = append(c.messages, messages...)
+	if len(c.messages) > maxConversationMessages {
+		c.messages = slices.Clone(c.messages[len(c.messages)-maxConversationMessages:])
+	}
+
+	c.lastAccess = time.Now()
+}
+
+// evict removes expired conversations and, if the store is still full, the least recently used one.
+func (s *conversationStore) evict() {
+	var (
+		oldestID string
+		oldest   time.Time
+	)
+
+	for id, c := range s.conversations {
+		if time.Since(c.lastAccess) > conversationTTL {
+			delete(s.conversations, id)
+			continue
+		}
+
+		if oldestID == "" || c.lastAccess.Before(oldest) {
+			oldestID = id
+			oldest = c.lastAccess
+		}
+	}
+
+	if len(s.conversations) >= maxConversations {
+		delete(s.conversations, oldestID)
+	}
+}
@@ -0,0 +1,299 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgoltzsche/knowledgebot/internal/qna"
+)
+
+// modelID is the model name under which the knowledge bot is exposed via the OpenAI-compatible API.
+const (
+	modelID = "knowledgebot"
+	// internalErrorMessage is returned to clients instead of internal error details, which are logged.
+	internalErrorMessage = "internal server error"
+)
+
+type chatCompletionRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Stream      bool          `json:"stream"`
+	Temperature *float64      `json:"temperature,omitempty"`
+}
+
+type chatMessage struct {
+	Role    string      `json:"role"`
+	Content chatContent `json:"content"`
+}
+
+// chatContent is a message's content that is specified either as string or as list of content parts.
+type chatContent string
+
+func (c *chatContent) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*c = chatContent(s)
+		return nil
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+
+	err := json.Unmarshal(b, &parts)
+	if err != nil {
+		return errors.New("message content must be a string or a list of content parts")
+	}
+
+	texts := make([]string, 0, len(parts))
+
+	for _, p := range parts {
+		if p.Type == "text" {
+			texts = append(texts, p.Text)
+		}
+	}
+
+	*c = chatContent(strings.Join(texts, "\n"))
+
+	return nil
+}
+
+type chatCompletion struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"`
+	Created int64        `json:"created"`
+	Model   string       `json:"model"`
+	Choices []chatChoice `json:"choices"`
+	// Sources and Citations are extensions of the OpenAI API.
+	Sources   []qna.SourceReference `json:"sources,omitempty"`
+	Citations []qna.Citation        `json:"citations,omitempty"`
+}
+
+type chatChoice struct {
+	Index        int             `json:"index"`
+	Message      *chatMessageOut `json:"message,omitempty"`
+	Delta        *chatMessageOut `json:"delta,omitempty"`
+	FinishReason *string         `json:"finish_reason"`
+}
+
+type chatMessageOut struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+type openAIErrorResponse struct {
+	Error openAIError `json:"error"`
+}
+
+type openAIError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+func newModelsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"object": "list",
+			"data": []map[string]any{{
+				"id":       modelID,
+				"object":   "model",
+				"created":  0,
+				"owned_by": modelID,
+			}},
+		})
+	})
+}
+
+func newChatCompletionsHandler(ai *qna.QuestionAnswerWorkflow) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			writeOpenAIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+			return
+		}
+
+		var r chatCompletionRequest
+
+		err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxRequestBodySize)).Decode(&r)
+		if err != nil {
+			writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "invalid request body: "+err.Error())
+			return
+		}
+
+		question, history, err := questionFromMessages(r.Messages)
+		if err != nil {
+			writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+			return
+		}
+
+		ch, err := ai.Answer(req.Context(), qna.Request{
+			Question:    question,
+			History:     history,
+			Temperature: r.Temperature,
+		})
+		if err != nil {
+			if errors.Is(err, qna.ErrInvalidRequest) {
+				writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+				return
+			}
+
+			slog.Error(err.Error())
+			writeOpenAIError(w, http.StatusInternalServerError, "server_error", internalErrorMessage)
+
+			return
+		}
+
+		completion := chatCompletion{
+			ID:      "chatcmpl-" + uuid.NewString(),
+			Created: time.Now().Unix(),
+			Model:   modelID,
+		}
+
+		if r.Stream {
+			streamChatCompletion(w, completion, ch)
+		} else {
+			writeChatCompletion(w, completion, ch)
+		}
+	})
+}
+
+// questionFromMessages returns the last user message as question and the previous user and assistant messages as history.
+// System messages are ignored since the knowledge bot uses its own system prompt.
+func questionFromMessages(msgs []chatMessage) (string, []qna.Message, error) {
+	history := make([]qna.Message, 0, len(msgs))
+
+	for _, m := range msgs {
+		switch m.Role {
+		case qna.RoleUser, qna.RoleAssistant:
+			history = append(history, qna.Message{Role: m.Role, Content: string(m.Content)})
+		case "system", "developer":
+		default:
+			return "", nil, fmt.Errorf("unsupported message role %q", m.Role)
+		}
+	}
+
+	if len(history) == 0 || history[len(history)-1].Role != qna.RoleUser {
+		return "", nil, errors.New("the last message must be a user message")
+	}
+
+	question := history[len(history)-1].Content
+	if strings.TrimSpace(question) == "" {
+		return "", nil, errors.New("the user message must not be empty")
+	}
+
+	return question, history[:len(history)-1], nil
+}
+
+func writeChatCompletion(w http.ResponseWriter, completion chatCompletion, ch <-chan qna.ResponseChunk) {
+	var answer strings.Builder
+
+	for chunk := range ch {
+		if chunk.Err != nil {
+			slog.Error(chunk.Err.Error())
+			writeOpenAIError(w, http.StatusInternalServerError, "server_error", internalErrorMessage)
+
+			for range ch {
+			}
+
+			return
+		}
+
+		answer.WriteString(chunk.Chunk)
+
+		if chunk.Sources != nil {
+			completion.Sources = chunk.Sources
+		}
+
+		if chunk.Citation != nil {
+			completion.Citations = append(completion.Citations, *chunk.Citation)
+		}
+	}
+
+	stop := "stop"
+	completion.Object = "chat.completion"
+	completion.Choices = []chatChoice{{
+		Message:      &chatMessageOut{Role: qna.RoleAssistant, Content: answer.String()},
+		FinishReason: &stop,
+	}}
+
+	writeJSON(w, http.StatusOK, completion)
+}
+
+func streamChatCompletion(w http.ResponseWriter, completion chatCompletion, ch <-chan qna.ResponseChunk) {
+	setHeaders(w.Header())
+
+	completion.Object = "chat.completion.chunk"
+	first := completion
+	first.Choices = []chatChoice{{Delta: &chatMessageOut{Role: qna.RoleAssistant}}}
+	writeEvent(w, first)
+
+	for chunk := range ch {
+		c := completion
+		c.Choices = []chatChoice{{Delta: &chatMessageOut{Content: chunk.Chunk}}}
+
+		switch {
+		case chunk.Err != nil:
+			slog.Error(chunk.Err.Error())
+			writeEvent(w, openAIErrorResponse{Error: openAIError{Message: internalErrorMessage, Type: "server_error"}})
+
+			for range ch {
+			}
+
+			return
+		case chunk.Sources != nil:
+			c.Sources = chunk.Sources
+		case chunk.Citation != nil:
+			c.Citations = []qna.Citation{*chunk.Citation}
+		case chunk.Chunk == "":
+			continue
+		}
+
+		writeEvent(w, c)
+	}
+
+	stop := "stop"
+	last := completion
+	last.Choices = []chatChoice{{Delta: &chatMessageOut{}, FinishReason: &stop}}
+	writeEvent(w, last)
+
+	_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes the given value as server-sent event.
+func writeEvent(w http.ResponseWriter, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("failed to marshal event: " + err.Error())
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "data: %s\n\n", string(data))
+
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func writeOpenAIError(w http.ResponseWriter, status int, errType, msg string) {
+	writeJSON(w, status, openAIErrorResponse{Error: openAIError{Message: msg, Type: errType}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		slog.Warn("write response: " + err.Error())
+	}
+}
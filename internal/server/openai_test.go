@@ -0,0 +1,66 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mgoltzsche/knowledgebot/internal/qna"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuestionFromMessages(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		messages       string
+		expectQuestion string
+		expectHistory  []qna.Message
+		expectError    bool
+	}{
+		{
+			name:           "single question",
+			messages:       `[{"role":"system","content":"ignored"},{"role":"user","content":"Who is Fry?"}]`,
+			expectQuestion: "Who is Fry?",
+			expectHistory:  []qna.Message{},
+		},
+		{
+			name: "follow-up question with content parts",
+			messages: `[
+				{"role":"user","content":"Who is Fry?"},
+				{"role":"assistant","content":"A delivery boy."},
+				{"role":"user","content":[{"type":"text","text":"Who voiced him?"}]}
+			]`,
+			expectQuestion: "Who voiced him?",
+			expectHistory: []qna.Message{
+				{Role: qna.RoleUser, Content: "Who is Fry?"},
+				{Role: qna.RoleAssistant, Content: "A delivery boy."},
+			},
+		},
+		{
+			name:        "last message from assistant",
+			messages:    `[{"role":"user","content":"Who is Fry?"},{"role":"assistant","content":"A delivery boy."}]`,
+			expectError: true,
+		},
+		{
+			name:        "unsupported role",
+			messages:    `[{"role":"tool","content":"42"},{"role":"user","content":"Who is Fry?"}]`,
+			expectError: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var msgs []chatMessage
+
+			err := json.Unmarshal([]byte(tc.messages), &msgs)
+			require.NoError(t, err)
+
+			question, history, err := questionFromMessages(msgs)
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expectQuestion, question, "question")
+			require.Equal(t, tc.expectHistory, history, "history")
+		})
+	}
+}
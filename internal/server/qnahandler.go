@@ -2,59 +2,153 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"mime"
 	"net/http"
+	"strings"
 
 	"github.com/mgoltzsche/knowledgebot/internal/qna"
 )
 
-func newQuestionAnswerHandler(ai *qna.QuestionAnswerWorkflow) http.Handler {
+const (
+	// maxRequestBodySize is the maximum size of a JSON request body.
+	maxRequestBodySize = 1 << 20
+	// newConversation is the conversation ID a client specifies to start a conversation that the server keeps.
+	newConversation = "new"
+)
+
+// questionAnswerRequest is the JSON request body of the question answering endpoint.
+type questionAnswerRequest struct {
+	qna.Request
+	// Conversation is the ID of the conversation to continue or "new" to start a conversation.
+	// Without it, the server does not keep the conversation.
+	// It is ignored when the request specifies the history explicitly.
+	Conversation string `json:"conversation,omitempty"`
+}
+
+func newQuestionAnswerHandler(ai *qna.QuestionAnswerWorkflow, conversations *conversationStore) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		question := req.URL.Query().Get("q")
-		if question == "" {
-			err := req.ParseForm()
-			if err != nil {
-				slog.Warn("parse form data: " + err.Error())
-			}
+		r, err := parseQuestionAnswerRequest(w, req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Conversations are kept on the server only when the client asks for it and does not maintain the history itself.
+		conversationID := ""
+
+		if len(r.History) == 0 && r.Conversation != "" {
+			var ok bool
+
+			conversationID = r.Conversation
 
-			question = req.Form.Get("q")
-			if question == "" {
-				http.Error(w, "parameter q not specified", http.StatusBadRequest)
-				return
+			r.History, ok = conversations.History(conversationID)
+			if !ok {
+				// Either a new or an expired conversation.
+				conversationID = conversations.NewConversation()
 			}
 		}
 
-		ch, err := ai.Answer(req.Context(), question)
+		ch, err := ai.Answer(req.Context(), r.Request)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			status := http.StatusInternalServerError
+			if errors.Is(err, qna.ErrInvalidRequest) {
+				status = http.StatusBadRequest
+			}
+
+			http.Error(w, err.Error(), status)
+
 			return
 		}
 
 		setHeaders(w.Header())
 
+		var (
+			answer strings.Builder
+			failed bool
+		)
+
+		if conversationID != "" {
+			writeChunk(w, qna.ResponseChunk{ConversationID: conversationID})
+		}
+
 		for chunk := range ch {
+			answer.WriteString(chunk.Chunk)
+
 			if chunk.Err != nil {
+				failed = true
 				chunk.Err = exposedError(chunk.Err.Error())
 			}
 
-			data, err := json.Marshal(chunk)
-			if err != nil {
-				slog.Error("failed to marshal chunk: " + err.Error())
-				continue
-			}
+			writeChunk(w, chunk)
+		}
 
-			if chunk.Err != nil {
-				_, _ = fmt.Fprintln(w, "event: error")
-			}
+		if conversationID != "" && !failed && req.Context().Err() == nil {
+			conversations.Append(conversationID,
+				qna.Message{Role: qna.RoleUser, Content: r.Question},
+				qna.Message{Role: qna.RoleAssistant, Content: answer.String()},
+			)
+		}
+	})
+}
 
-			_, _ = fmt.Fprintf(w, "data: %s\n\n", string(data))
+// parseQuestionAnswerRequest reads the request from the JSON body of a POST request or from the query or form parameters.
+func parseQuestionAnswerRequest(w http.ResponseWriter, req *http.Request) (*questionAnswerRequest, error) {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
 
-			if flusher, ok := w.(http.Flusher); ok {
-				flusher.Flush()
-			}
+	if req.Method == http.MethodPost && mediaType == "application/json" {
+		var r questionAnswerRequest
+
+		err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxRequestBodySize)).Decode(&r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid request body: %w", err)
 		}
-	})
+
+		if r.Question == "" {
+			return nil, errors.New("question not specified")
+		}
+
+		return &r, nil
+	}
+
+	question := req.URL.Query().Get("q")
+	if question == "" {
+		err := req.ParseForm()
+		if err != nil {
+			slog.Warn("parse form data: " + err.Error())
+		}
+
+		question = req.Form.Get("q")
+		if question == "" {
+			return nil, errors.New("parameter q not specified")
+		}
+	}
+
+	return &questionAnswerRequest{
+		Request:      qna.Request{Question: question},
+		Conversation: req.FormValue("conversation"),
+	}, nil
+}
+
+// writeChunk writes the given response chunk as server-sent event.
+func writeChunk(w http.ResponseWriter, chunk qna.ResponseChunk) {
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		slog.Error("failed to marshal chunk: " + err.Error())
+		return
+	}
+
+	if chunk.Err != nil {
+		_, _ = fmt.Fprintln(w, "event: error")
+	}
+
+	_, _ = fmt.Fprintf(w, "data: %s\n\n", string(data))
+
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
 }
 
 type exposedError string
@@ -14,5 +14,8 @@ type Routes struct {
 func (r *Routes) AddRoutes(mux *http.ServeMux) {
 	mux.Handle("/", http.RedirectHandler("/ui/", http.StatusTemporaryRedirect))
 	mux.Handle("/ui/", http.StripPrefix("/ui/", http.FileServer(http.Dir(r.WebDir))))
-	mux.Handle("/api/qna", newQuestionAnswerHandler(r.Workflow))
+	mux.Handle("/api/qna", newQuestionAnswerHandler(r.Workflow, newConversationStore()))
+	mux.Handle("/api/search", newSearchHandler(r.Workflow))
+	mux.Handle("/v1/chat/completions", newChatCompletionsHandler(r.Workflow))
+	mux.Handle("/v1/models", newModelsHandler())
 }
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/mgoltzsche/knowledgebot/internal/qna"
+)
+
+const (
+	// maxSearchLimit is the maximum number of chunks a search request can return.
+	maxSearchLimit = 100
+	// maxSearchOffset is the maximum number of chunks a search request can skip.
+	maxSearchOffset = 1000
+)
+
+func newSearchHandler(ai *qna.QuestionAnswerWorkflow) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		query := req.FormValue("q")
+		if query == "" {
+			http.Error(w, "parameter q not specified", http.StatusBadRequest)
+			return
+		}
+
+		searchReq := qna.SearchRequest{
+			Query:          query,
+			Limit:          ai.MaxDocs,
+			ScoreThreshold: ai.ScoreThreshold,
+		}
+
+		err := parseIntParam(req, "limit", &searchReq.Limit, 1, maxSearchLimit)
+		if err == nil {
+			err = parseIntParam(req, "offset", &searchReq.Offset, 0, maxSearchOffset)
+		}
+
+		if err == nil {
+			err = parseFloatParam(req, "threshold", &searchReq.ScoreThreshold, 0, 1)
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		resp, err := ai.Search(req.Context(), searchReq)
+		if err != nil {
+			slog.Error(err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
+
+func parseIntParam(req *http.Request, name string, value *int, minValue, maxValue int) error {
+	s := req.FormValue(name)
+	if s == "" {
+		return nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < minValue || v > maxValue {
+		return fmt.Errorf("parameter %s must be an integer between %d and %d", name, minValue, maxValue)
+	}
+
+	*value = v
+
+	return nil
+}
+
+func parseFloatParam(req *http.Request, name string, value *float64, minValue, maxValue float64) error {
+	s := req.FormValue(name)
+	if s == "" {
+		return nil
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < minValue || v > maxValue {
+		return fmt.Errorf("parameter %s must be a number between %g and %g", name, minValue, maxValue)
+	}
+
+	*value = v
+
+	return nil
+}
@@ -0,0 +1,116 @@
+// Package tokens approximates how many tokens a model splits a text into.
+package tokens
+
+import (
+	"strings"
+	"unicode"
+)
+
+// wordPieceLength is the number of characters per token of a word.
+// It is chosen conservatively since WordPiece splits long and rare words into short pieces.
+const wordPieceLength = 4
+
+// modelMaxTokens maps embedding model names to their maximum input length in tokens.
+var modelMaxTokens = map[string]int{
+	"all-minilm":                 256,
+	"all-minilm-l6-v2":           256,
+	"all-minilm-l12-v2":          256,
+	"nomic-embed-text":           8192,
+	"mxbai-embed-large":          512,
+	"snowflake-arctic-embed":     512,
+	"snowflake-arctic-embed2":    8192,
+	"bge-m3":                     8192,
+	"bge-large":                  512,
+	"granite-embedding":          512,
+	"text-embedding-ada-002":     8191,
+	"text-embedding-3-small":     8191,
+	"text-embedding-3-large":     8191,
+	"paraphrase-multilingual":    128,
+	"multilingual-e5-large":      512,
+	"jina-embeddings-v2-base-en": 8192,
+}
+
+// Estimate returns the approximate number of tokens that a WordPiece or BPE tokenizer splits the given text into.
+// Words count one token per started 4 characters, CJK characters, punctuation and symbols count one token each.
+// Since the estimate is meant to keep texts within a model's input limit, it rather errs on the high side for common English text,
+// but it can still undercount texts consisting of rare words or identifiers that are split into even shorter pieces.
+func Estimate(text string) int {
+	c := counter{}
+
+	for _, r := range text {
+		c.add(r)
+	}
+
+	return c.count
+}
+
+// Truncate returns the longest prefix of the given text that is estimated to consist of at most maxTokens tokens.
+// The text is cut at a whitespace if possible.
+func Truncate(text string, maxTokens int) string {
+	c := counter{}
+	lastSpace := -1
+
+	for i, r := range text {
+		if c.add(r) && c.count > maxTokens {
+			if lastSpace > 0 {
+				return text[:lastSpace]
+			}
+
+			return text[:i]
+		}
+
+		if unicode.IsSpace(r) {
+			lastSpace = i
+		}
+	}
+
+	return text
+}
+
+// counter counts the tokens of a text rune by rune.
+type counter struct {
+	count   int
+	wordLen int
+}
+
+// add counts the given rune and returns true if it starts a new token.
+func (c *counter) add(r rune) bool {
+	switch {
+	case isCJK(r):
+		c.wordLen = 0
+	case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+		c.wordLen++
+		if (c.wordLen-1)%wordPieceLength != 0 {
+			return false
+		}
+	case unicode.IsSpace(r):
+		c.wordLen = 0
+		return false
+	default:
+		c.wordLen = 0
+	}
+
+	c.count++
+
+	return true
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// ModelMaxTokens returns the maximum input length of the given embedding model in tokens or 0 if it is unknown.
+// The model name's tag and namespace, e.g. "library/all-minilm:l6-v2", are ignored.
+func ModelMaxTokens(model string) int {
+	model = strings.ToLower(model)
+
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+
+	if i := strings.Index(model, ":"); i >= 0 {
+		model = model[:i]
+	}
+
+	return modelMaxTokens[model]
+}
@@ -0,0 +1,54 @@
+package tokens
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimate(t *testing.T) {
+	for _, tc := range []struct {
+		input  string
+		expect int
+	}{
+		{"", 0},
+		{"  \n\t", 0},
+		{"Fry", 1},
+		{"Fry and Leela", 4},
+		{"Farnsworth", 3},
+		{"Planet Express, Inc.", 7},
+		{"## Episodes", 4},
+		{"ロボット", 4},
+		{"year 3000", 2},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			require.Equal(t, tc.expect, Estimate(tc.input))
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, tc := range []struct {
+		input     string
+		maxTokens int
+		expect    string
+	}{
+		{"Fry and Leela", 4, "Fry and Leela"},
+		{"Fry and Leela", 3, "Fry and"},
+		{"Fry and Leela", 2, "Fry and"},
+		{"Fry and Leela", 0, ""},
+		{"Farnsworth", 1, "Farn"},
+		{"ロボット", 2, "ロボ"},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			require.Equal(t, tc.expect, Truncate(tc.input, tc.maxTokens))
+		})
+	}
+}
+
+func TestModelMaxTokens(t *testing.T) {
+	require.Equal(t, 256, ModelMaxTokens("all-minilm"))
+	require.Equal(t, 256, ModelMaxTokens("all-minilm:l6-v2"))
+	require.Equal(t, 8192, ModelMaxTokens("library/Nomic-Embed-Text:latest"))
+	require.Equal(t, 0, ModelMaxTokens("unknown-model"))
+}